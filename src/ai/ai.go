@@ -1,9 +1,6 @@
 package ai
 
-import (
-    "math/rand"
-    "time"
-)
+import "rng"
 
 /*
  * Taken and inspired by appliedgo.net/perceptron
@@ -47,16 +44,14 @@ type Perceptron struct {
  *  A pointer to a Perceptron with n random weights and a random bias.
  */
 func CreatePerceptron(n int, low, high float32) *Perceptron {
-    r := rand.New(rand.NewSource(time.Now().UnixNano()))
-
     weights := make([]float32, n, n)
     for i := range weights {
-        weights[i] = r.Float32() * (high - low) + low
+        weights[i] = rng.Rand().Float32() * (high - low) + low
     }
 
     return &Perceptron {
         weights,
-        r.Float32() * (high - low) + low,
+        rng.Rand().Float32() * (high - low) + low,
     }
 }
 
@@ -0,0 +1,422 @@
+package ai
+
+import "math"
+
+
+/*
+ * A TSEngine that can also say which player is to move in a state, and which
+ * side a player is on. Alpha-mu needs this to tell the searching player's own
+ * moves, which must be the same in every world, apart from everybody else's.
+ */
+type MoverEngine interface {
+    TSEngine
+    Mover(state TSState) int
+    FavorablePlayer(player int) bool
+}
+
+
+/*
+ * A set of result vectors that alpha-mu keeps at a node. Each vector has one
+ * entry per world, which is the evaluation for the searching player in that
+ * world if the searching player follows some fixed strategy below the node.
+ * Worlds that are not consistent with the node are NaN. Only vectors that are
+ * not dominated by another vector in the set are kept, so it is a Pareto
+ * front.
+ */
+type front [][]float64
+
+
+/*
+ * Performs alpha-mu search over a set of determinizations. Like PIMC, the
+ * other players are assumed to know the true world and play optimally in it.
+ * Unlike PIMC, the searching player must pick the same move in every world for
+ * its next maxMoves moves, since it can not tell the worlds apart. This removes
+ * strategy fusion for those moves. The results of every strategy are kept as
+ * Pareto fronts of per world evaluations, and once the searching player has no
+ * moves left to search, each world is solved exactly with minimax. An opponent
+ * node is cut off as soon as its front is dominated by what the searching
+ * player can already get, since the rest of its moves can only make it worse.
+ * With maxMoves of 1, this is the same as PIMC, and as with PIMC every world is
+ * searched when there are no more than deters of them.
+ *
+ * The searching player does not have to be the player to move. In that case
+ * there is no move to choose, and only the value of the state is of use.
+ *
+ * Args:
+ *  s: The current state to search from. This should not be terminal.
+ *  engine: The game engine with which to step through game logic.
+ *  searcher: The player that the search is for. This player's hand should be
+ *            known in s.
+ *  deters: The number of determinizations, or worlds, to search over.
+ *  maxMoves: The number of the searching player's own moves to search before
+ *            solving each world separately. Must be at least 1.
+ *
+ * Returns:
+ *  The move whose best strategy has the highest average evaluation across the
+ *  worlds, and that average. The average is from the point of view of the
+ *  player to move, as in MCTS. If the searcher is not the player to move, the
+ *  move is empty.
+ */
+func AlphaMu(s State, engine MoverEngine, searcher int, deters int,
+             maxMoves int) (Move, float64) {
+    deals := Worlds(s, deters)
+    worlds := make([]TSState, len(deals))
+    weights := make([]float64, len(deals))
+    for i, deal := range deals {
+        worlds[i] = deal.State
+        weights[i] = deal.Weight
+    }
+
+    // The evaluations are kept from the point of view of the searcher's side.
+    fav := engine.FavorablePlayer(searcher)
+    if engine.Mover(worlds[0]) != searcher {
+        states := make([]TSState, len(worlds))
+        copy(states, worlds)
+        f := alphaMuHelper(states, engine, searcher, fav, maxMoves, nil)
+        score := f.score(weights)
+        if engine.Favorable(worlds[0]) != fav {
+            score = -score
+        }
+
+        return Move{ }, score
+    }
+
+    // A move whose front is dominated by the fronts of the moves before it
+    // can not score higher than them, so those fronts can cut it off.
+    var maxMove Move
+    var searched front
+    maxScore := math.Inf(-1)
+    for _, move := range engine.Successors(worlds[0]) {
+        children := successorsWithAction(worlds, engine, move.Action)
+        f := alphaMuHelper(children, engine, searcher, fav, maxMoves - 1,
+                           searched)
+
+        score := f.score(weights)
+        if score > maxScore {
+            maxMove = move
+            maxScore = score
+        }
+        searched = append(searched, f...).pareto()
+    }
+
+    return maxMove, maxScore
+}
+
+
+/*
+ * The recursive part of alpha-mu. The states are the worlds at this node, with
+ * a nil entry for every world that is not consistent with it.
+ *
+ * Args:
+ *  states: The state in each world, or nil if the world is no longer possible.
+ *  engine: The game engine with which to step through game logic.
+ *  searcher: The player that the search is for.
+ *  fav: If the searcher's team is the favorable one.
+ *  m: The number of the searcher's moves left to search.
+ *  alpha: The front the searcher already has at the node above, or nil. If
+ *         this is an opponent's node, it is cut off once its front is
+ *         dominated by alpha.
+ *
+ * Returns:
+ *  The Pareto front of evaluation vectors for the node. If the node was cut
+ *  off, every vector in it is dominated by alpha.
+ */
+func alphaMuHelper(states []TSState, engine MoverEngine, searcher int,
+                   fav bool, m int, alpha front) front {
+    var first TSState
+    for _, state := range states {
+        if state != nil {
+            first = state
+            break
+        }
+    }
+
+    // Once the searcher has no more moves to search, or the game is over,
+    // every world is solved on its own.
+    if m == 0 || engine.IsTerminal(first) {
+        v := make([]float64, len(states))
+        for i, state := range states {
+            if state == nil {
+                v[i] = math.NaN()
+                continue
+            }
+
+            if engine.IsTerminal(state) {
+                v[i] = engine.Evaluation(state)
+            } else {
+                v[i], _ = Minimax(state, engine)
+            }
+
+            if !fav {
+                v[i] = -v[i]
+            }
+        }
+
+        return front{ v }
+    }
+
+    // The searcher can not tell the worlds apart, so a move must be the same
+    // across all of them. The searcher can choose any strategy of any move,
+    // so the front is the union of the fronts of the moves, and the front so
+    // far can cut off the moves after it.
+    if engine.Mover(first) == searcher {
+        var result front
+        for _, move := range engine.Successors(first) {
+            children := successorsWithAction(states, engine, move.Action)
+            result = append(result, alphaMuHelper(children, engine, searcher,
+                                                  fav, m - 1, result)...)
+            result = result.pareto()
+        }
+
+        return result
+    }
+
+    // Any other player is assumed to know the world, so in each world it
+    // picks the best move for its side. The worlds where a move is not legal
+    // do not take part in that move's subtree.
+    partner := engine.Favorable(first) == fav
+    actions := make([]interface{}, 0)
+    seen := make(map[interface{}]bool)
+    for _, state := range states {
+        if state == nil {
+            continue
+        }
+
+        for _, move := range engine.Successors(state) {
+            if !seen[move.Action] {
+                seen[move.Action] = true
+                actions = append(actions, move.Action)
+            }
+        }
+    }
+
+    // Once an opponent's front covers every world, each further move can only
+    // lower it, so if the searcher already has something at least as good,
+    // the rest of the moves need not be searched. A cut off front is only
+    // passed to an opponent node directly below the searcher, since a partner
+    // raises the front again, and a node below can be missing worlds.
+    var result front
+    for _, action := range actions {
+        children := successorsWithAction(states, engine, action)
+        f := alphaMuHelper(children, engine, searcher, fav, m, nil)
+
+        if result == nil {
+            result = f
+        } else {
+            result = result.combine(f, partner).pareto()
+        }
+
+        if !partner && alpha != nil && result.covers(states) &&
+           result.dominatedBy(alpha) {
+            break
+        }
+    }
+
+    return result
+}
+
+
+/*
+ * Find the successor of every world's state through the given action.
+ *
+ * Args:
+ *  states: The states of each world, with nil for worlds that are not possible.
+ *  engine: The game engine with which to step through game logic.
+ *  action: The action to take in every world.
+ *
+ * Returns:
+ *  The successor in each world, with nil where the world was not possible or
+ *  the action is not legal in it.
+ */
+func successorsWithAction(states []TSState, engine TSEngine,
+                          action interface{}) []TSState {
+    children := make([]TSState, len(states))
+    for i, state := range states {
+        if state == nil {
+            continue
+        }
+
+        for _, move := range engine.Successors(state) {
+            if move.Action == action {
+                children[i] = move.State
+                break
+            }
+        }
+    }
+
+    return children
+}
+
+
+/*
+ * Combine two fronts at a node where the player to move knows the world. Any
+ * pair of strategies, one from each front, can be followed, and in every world
+ * the player to move then picks the better of the two for its own side.
+ *
+ * Args:
+ *  other: The front to combine with.
+ *  max: If the player to move maximizes the searcher's evaluation.
+ *
+ * Returns:
+ *  The front of every combined pair. This front is not reduced.
+ */
+func (f front) combine(other front, max bool) front {
+    result := make(front, 0, len(f) * len(other))
+    for _, u := range f {
+        for _, v := range other {
+            w := make([]float64, len(u))
+            for i := range u {
+                if math.IsNaN(u[i]) {
+                    w[i] = v[i]
+                } else if math.IsNaN(v[i]) {
+                    w[i] = u[i]
+                } else if max {
+                    w[i] = math.Max(u[i], v[i])
+                } else {
+                    w[i] = math.Min(u[i], v[i])
+                }
+            }
+
+            result = append(result, w)
+        }
+    }
+
+    return result
+}
+
+
+/*
+ * Reduce a front to the vectors that are not dominated by any other vector. A
+ * vector dominates another if it is at least as good in every world. Only one
+ * copy of equal vectors is kept.
+ *
+ * Returns:
+ *  The Pareto front of the vectors.
+ */
+func (f front) pareto() front {
+    result := make(front, 0, len(f))
+    for i, u := range f {
+        dominated := false
+        for j, v := range f {
+            if i != j && dominates(v, u) && (!dominates(u, v) || j < i) {
+                dominated = true
+                break
+            }
+        }
+
+        if !dominated {
+            result = append(result, u)
+        }
+    }
+
+    return result
+}
+
+
+/*
+ * Checks if every vector of a front has a value in every world that is
+ * possible.
+ *
+ * Args:
+ *  states: The state of each world, with nil for worlds that are not possible.
+ *
+ * Returns:
+ *  True if no vector is NaN in a world whose state is not nil.
+ */
+func (f front) covers(states []TSState) bool {
+    for _, v := range f {
+        for i, state := range states {
+            if state != nil && math.IsNaN(v[i]) {
+                return false
+            }
+        }
+    }
+
+    return true
+}
+
+
+/*
+ * Checks if every vector of a front is dominated by some vector of another.
+ *
+ * Args:
+ *  alpha: The front that may dominate.
+ *
+ * Returns:
+ *  True if every vector of f is dominated by a vector of alpha.
+ */
+func (f front) dominatedBy(alpha front) bool {
+    for _, u := range f {
+        dominated := false
+        for _, a := range alpha {
+            if dominates(a, u) {
+                dominated = true
+                break
+            }
+        }
+
+        if !dominated {
+            return false
+        }
+    }
+
+    return true
+}
+
+
+/*
+ * The score of a front is the best weighted average evaluation over the
+ * possible worlds of any of its vectors.
+ *
+ * Args:
+ *  weights: The weight of each world, or nil for the same weight for all.
+ *
+ * Returns:
+ *  The score of the front, or -inf if it is empty.
+ */
+func (f front) score(weights []float64) float64 {
+    best := math.Inf(-1)
+    for _, v := range f {
+        sum := 0.0
+        total := 0.0
+        for i, x := range v {
+            if !math.IsNaN(x) {
+                w := 1.0
+                if weights != nil {
+                    w = weights[i]
+                }
+
+                sum += w * x
+                total += w
+            }
+        }
+
+        if total > 0 && sum / total > best {
+            best = sum / total
+        }
+    }
+
+    return best
+}
+
+
+/*
+ * Checks if vector u is at least as good as vector v in every world. Worlds
+ * that are NaN in either vector are not compared.
+ *
+ * Args:
+ *  u: The vector that may dominate.
+ *  v: The vector that may be dominated.
+ *
+ * Returns:
+ *  True if u is greater than or equal to v in every world.
+ */
+func dominates(u, v []float64) bool {
+    for i := range u {
+        if !math.IsNaN(u[i]) && !math.IsNaN(v[i]) && u[i] < v[i] {
+            return false
+        }
+    }
+
+    return true
+}
@@ -0,0 +1,115 @@
+package ai
+
+import (
+    "math"
+    "testing"
+)
+
+
+/*
+ * Test that the Pareto front of a set of vectors drops the dominated vectors
+ * and duplicates, and ignores the worlds that are not possible.
+ */
+func TestPareto(t *testing.T) {
+    nan := math.NaN()
+    f := front {
+        []float64 { 1, 2, nan },
+        []float64 { 2, 2, nan },
+        []float64 { 0, 3, nan },
+        []float64 { 2, 2, nan },
+        []float64 { -1, 1, nan },
+    }
+
+    res := f.pareto()
+    if len(res) != 2 {
+        t.Fatalf("Expected 2 vectors in the front not %d.\n", len(res))
+    }
+
+    if res[0][0] != 2 || res[0][1] != 2 || res[1][0] != 0 || res[1][1] != 3 {
+        t.Errorf("Gave %v instead of [[2 2 NaN] [0 3 NaN]].\n", res)
+    }
+}
+
+
+/*
+ * Test that combining fronts takes the extreme of every pair in each world, and
+ * that a world missing from one vector takes the value from the other.
+ */
+func TestCombine(t *testing.T) {
+    nan := math.NaN()
+    f := front {
+        []float64 { 1, 4, nan },
+    }
+    other := front {
+        []float64 { 2, nan, 1 },
+        []float64 { 0, 3, nan },
+    }
+
+    min := f.combine(other, false)
+    expected := [][]float64 {
+        []float64 { 1, 4, 1 },
+        []float64 { 0, 3, nan },
+    }
+    for i := range expected {
+        for j := range expected[i] {
+            e, a := expected[i][j], min[i][j]
+            if e != a && !(math.IsNaN(e) && math.IsNaN(a)) {
+                t.Errorf("Gave %v instead of %v.\n", min, expected)
+            }
+        }
+    }
+
+    max := f.combine(other, true)
+    if max[0][0] != 2 || max[1][1] != 4 {
+        t.Errorf("Gave %v for the maximum combination.\n", max)
+    }
+}
+
+
+/*
+ * Test that the score of a front is the best average over the possible worlds.
+ */
+func TestScore(t *testing.T) {
+    nan := math.NaN()
+    f := front {
+        []float64 { 1, 2, nan },
+        []float64 { 0, 4, nan },
+    }
+
+    if f.score(nil) != 2 {
+        t.Errorf("Gave a score of %f instead of 2.\n", f.score(nil))
+    }
+}
+
+
+/*
+ * Test that a front is dominated by another only when each of its vectors is,
+ * and that it covers the worlds only when no vector is missing a possible one.
+ */
+func TestDominatedBy(t *testing.T) {
+    nan := math.NaN()
+    alpha := front {
+        []float64 { 2, 0, nan },
+        []float64 { 0, 2, nan },
+    }
+
+    tests := []struct {
+        f front
+        dominated bool
+        covers bool
+    } {
+        { front{ []float64{ 1, 0, nan }, []float64{ 0, 2, nan } }, true, true },
+        { front{ []float64{ 1, 0, nan }, []float64{ 1, 1, nan } }, false,
+          true },
+        { front{ []float64{ 2, nan, nan } }, true, false },
+    }
+
+    states := []TSState{ 0, 0, nil }
+    for i, test := range tests {
+        if test.f.dominatedBy(alpha) != test.dominated ||
+           test.f.covers(states) != test.covers {
+            t.Errorf("Case %d is dominated %t and covers %t.\n", i,
+                     test.f.dominatedBy(alpha), test.f.covers(states))
+        }
+    }
+}
@@ -0,0 +1,146 @@
+package ai
+
+import "sort"
+
+
+// The number of nodes that an arena allocates at a time.
+const ARENA_BLOCK = 4096
+
+
+/*
+ * The storage for the nodes of an MCTS tree. Nodes are handed out from large
+ * blocks rather than being allocated one at a time, which is much less work
+ * for the allocator and the garbage collector and keeps siblings next to each
+ * other in memory. A block is never grown once it is made, so a pointer to a
+ * node stays valid for as long as the tree does. widest is the most nodes
+ * reserved at once, and recycles is how many times the tree was recycled.
+ */
+type arena struct {
+    block []Node
+    used int
+    free map[int][][]Node
+    widest int
+    recycles int
+}
+
+
+/*
+ * Reserve room for a run of nodes that are next to each other, such as all of
+ * the children of one node. A run of the same size that was released is used
+ * again before any new room is taken.
+ *
+ * Args:
+ *  n: The number of nodes to reserve.
+ *
+ * Returns:
+ *  An empty slice with a capacity of n whose backing nodes are all unused.
+ */
+func (a *arena) reserve(n int) []Node {
+    a.used += n
+    if n > a.widest {
+        a.widest = n
+    }
+    if runs := a.free[n]; len(runs) > 0 {
+        a.free[n] = runs[:len(runs) - 1]
+        return runs[len(runs) - 1]
+    }
+
+    if cap(a.block) - len(a.block) < n {
+        size := ARENA_BLOCK
+        if n > size {
+            size = n
+        }
+
+        a.block = make([]Node, 0, size)
+    }
+
+    start := len(a.block)
+    a.block = a.block[:start + n]
+
+    return a.block[start:start:start + n]
+}
+
+
+/*
+ * Give a run of nodes back to the arena so that it can be reserved again. The
+ * nodes are cleared so that nothing they refer to is kept alive.
+ *
+ * Args:
+ *  run: A run that was returned by reserve.
+ */
+func (a *arena) release(run []Node) {
+    run = run[:cap(run)]
+    for i := range run {
+        run[i] = Node{ }
+    }
+
+    if a.free == nil {
+        a.free = make(map[int][][]Node)
+    }
+    a.free[len(run)] = append(a.free[len(run)], run[:0])
+    a.used -= len(run)
+}
+
+
+/*
+ * Recycle the least visited subtrees of a tree until it takes up no more than
+ * the target number of nodes. A recycled subtree is cut off below its root,
+ * which becomes a leaf that keeps its own statistics and can be expanded again
+ * later. The children of the tree's root are never cut off.
+ *
+ * Args:
+ *  root: The root of the tree.
+ *  target: The number of nodes the tree should be reduced to.
+ */
+func recycle(root *Node, target int) {
+    root.arena.recycles++
+
+    expanded := make([]*Node, 0)
+    var collect func(node *Node)
+    collect = func(node *Node) {
+        for i := range node.children {
+            child := &node.children[i]
+            if child.children != nil {
+                expanded = append(expanded, child)
+                collect(child)
+            }
+        }
+    }
+    collect(root)
+
+    // A node has at least as many visits as any node below it, so with ties
+    // going to the deeper node, subtrees are cut from the bottom up.
+    sort.SliceStable(expanded, func(i, j int) bool {
+        if expanded[i].simulations != expanded[j].simulations {
+            return expanded[i].simulations < expanded[j].simulations
+        }
+
+        return expanded[i].depth > expanded[j].depth
+    })
+
+    for _, node := range expanded {
+        if root.arena.used <= target {
+            break
+        }
+
+        prune(node)
+    }
+}
+
+
+/*
+ * Release every node below the given node, which is left as a leaf.
+ *
+ * Args:
+ *  node: The node whose subtree is released.
+ */
+func prune(node *Node) {
+    for i := range node.children {
+        if node.children[i].children != nil {
+            prune(&node.children[i])
+        }
+    }
+
+    node.arena.release(node.children)
+    node.children = nil
+}
@@ -0,0 +1,157 @@
+package ai
+
+import "testing"
+
+
+/*
+ * Test that runs reserved from an arena never share nodes, even when a run
+ * needs a block of its own, and that nodes do not move as the arena grows.
+ */
+func TestArenaReserve(t *testing.T) {
+    a := &arena{ }
+    first := a.reserve(3)
+    first = first[:3]
+    node := &first[0]
+    node.simulations = 1
+
+    seen := make(map[*Node]bool)
+    for _, n := range []int{ 3, ARENA_BLOCK - 10, ARENA_BLOCK + 5, 1 } {
+        run := a.reserve(n)
+        if len(run) != 0 || cap(run) != n {
+            t.Fatalf("Reserved %d nodes with a length of %d and capacity %d.\n",
+                     n, len(run), cap(run))
+        }
+
+        run = run[:n]
+        for i := range run {
+            if seen[&run[i]] || &run[i] == node {
+                t.Fatalf("A node was reserved twice.\n")
+            }
+            seen[&run[i]] = true
+        }
+    }
+
+    if node.simulations != 1 {
+        t.Errorf("A node was changed by later reservations.\n")
+    }
+}
+
+
+/*
+ * Test that recycling cuts off the least visited subtrees first, from the
+ * bottom up, and leaves the children of the root alone.
+ */
+func TestRecycle(t *testing.T) {
+    root := NewNode()
+    root.simulations = 20
+
+    // Two children of the root, each with two children of their own, of which
+    // the first has one more child.
+    for i, visits := range []int{ 15, 5 } {
+        child := root.addChild(Move{ i, nil }, 2)
+        child.simulations = visits
+        for j := 0; j < 2; j++ {
+            grandchild := child.addChild(Move{ j, nil }, 2)
+            grandchild.simulations = visits / 2
+        }
+        child.children[0].addChild(Move{ 0, nil }, 1).simulations = 1
+    }
+
+    if root.TreeSize() != 8 {
+        t.Fatalf("The tree takes up %d nodes instead of 8.\n", root.TreeSize())
+    }
+
+    // The great grandchild under the least visited child goes first, and then
+    // the children of that child.
+    recycle(root, 6)
+    if root.TreeSize() != 5 || root.children[1].children != nil ||
+       root.children[0].children[0].children == nil {
+        t.Errorf("Recycling to 6 nodes left %d nodes.\n", root.TreeSize())
+    }
+
+    recycle(root, 0)
+    if root.TreeSize() != 2 || len(root.children) != 2 {
+        t.Errorf("Recycling everything left %d nodes.\n", root.TreeSize())
+    }
+
+    // Released runs are reserved again before new room is taken.
+    block := len(root.arena.block)
+    root.children[0].addChild(Move{ 0, nil }, 2)
+    if len(root.arena.block) != block || root.TreeSize() != 4 {
+        t.Errorf("A released run was not used again.\n")
+    }
+}
+
+
+/*
+ * A game in which every state has three moves, the players take turns, and
+ * the game ends after a fixed number of moves with no winner. The state is the
+ * number of moves made so far, and there is nothing hidden in it.
+ */
+type ternaryState int
+
+
+func (s ternaryState) Determinize() { }
+
+
+func (s ternaryState) Copy() State {
+    return s
+}
+
+
+type ternaryEngine struct {
+    length int
+}
+
+
+func (e ternaryEngine) Favorable(state TSState) bool {
+    return state.(ternaryState) % 2 == 0
+}
+
+
+func (e ternaryEngine) IsTerminal(state TSState) bool {
+    return int(state.(ternaryState)) >= e.length
+}
+
+
+func (e ternaryEngine) Evaluation(state TSState) float64 {
+    return 0
+}
+
+
+func (e ternaryEngine) Successors(state TSState) []Move {
+    moves := make([]Move, 3)
+    for i := range moves {
+        moves[i] = Move{ i, state.(ternaryState) + 1 }
+    }
+
+    return moves
+}
+
+
+/*
+ * Test that a capped tree is recycled once the next expansion would not fit,
+ * even when the tree never fills up to the cap exactly, and that it never goes
+ * over the cap.
+ */
+func TestRecycleBeforeFull(t *testing.T) {
+    root := NewNode()
+    root.Value(Move{ nil, ternaryState(0) })
+
+    // Every expansion takes three nodes, so the tree takes up 3, 6 and then 9
+    // nodes, and the next expansion would take it over 10.
+    opts := DefaultMCTSOptions()
+    opts.MaxNodes = 10
+    opts.Recycle = true
+    for i := 0; i < 200; i++ {
+        runPlayout(root, ternaryEngine{ 6 }, opts, false)
+        if root.TreeSize() > opts.MaxNodes {
+            t.Fatalf("The tree takes up %d nodes after %d playouts.\n",
+                     root.TreeSize(), i + 1)
+        }
+    }
+
+    if root.Recycles() == 0 {
+        t.Errorf("The tree was never recycled.\n")
+    }
+}
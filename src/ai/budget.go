@@ -0,0 +1,111 @@
+package ai
+
+import "math"
+
+
+/*
+ * When a search may stop before its budget is spent, or run past it. Every
+ * Interval playouts per tree, the root actions are checked, and the search
+ * stops once the best action is separated from every other, which is when the
+ * lower confidence bound of its mean beats the upper confidence bound of every
+ * other mean. The bounds are Z standard errors from the mean, and are only
+ * trusted once every action has MinVisits visits. If the budget is spent and
+ * the actions are still not separated, the decision is close, and the search
+ * goes on until they are or until Extend times the budget is spent.
+ *
+ * The zero value never stops early and never runs past the budget.
+ */
+type EarlyStop struct {
+    Z float64
+    Interval int
+    MinVisits int
+    Extend float64
+}
+
+
+/*
+ * Whether the search checks if it can stop early at all.
+ *
+ * Returns:
+ *  True if Z is positive.
+ */
+func (e EarlyStop) enabled() bool {
+    return e.Z > 0
+}
+
+
+/*
+ * The most playouts a search may run for the given budget.
+ *
+ * Args:
+ *  budget: The playouts the search would run without early stopping.
+ *
+ * Returns:
+ *  The budget, or Extend times the budget for close decisions.
+ */
+func (e EarlyStop) limit(budget int) int {
+    if !e.enabled() || e.Extend <= 1 {
+        return budget
+    }
+
+    return int(float64(budget) * e.Extend)
+}
+
+
+/*
+ * The playouts to run between checks for the given budget.
+ *
+ * Args:
+ *  budget: The playouts the search would run without early stopping.
+ *
+ * Returns:
+ *  Interval, or the whole budget if early stopping is off or Interval is not
+ *  positive.
+ */
+func (e EarlyStop) interval(budget int) int {
+    if !e.enabled() || e.Interval <= 0 {
+        return budget
+    }
+
+    return e.Interval
+}
+
+
+/*
+ * Whether the best of the given root actions is separated from the rest, so
+ * that more playouts are unlikely to change which action is picked.
+ *
+ * Args:
+ *  actions: The statistics of every root action.
+ *  z: The number of standard errors from a mean to its confidence bounds.
+ *  minVisits: The visits every action needs before its bounds are trusted.
+ *
+ * Returns:
+ *  True if there is only one action, or the lower bound of the action with the
+ *  highest mean is above the upper bound of every other action.
+ */
+func Separated(actions []ActionStats, z float64, minVisits int) bool {
+    if len(actions) == 1 {
+        return true
+    }
+
+    for _, action := range actions {
+        if action.Visits < minVisits || action.Visits == 0 {
+            return false
+        }
+    }
+
+    best := argmaxAction(actions, func(a ActionStats) float64 {
+        return a.Mean
+    })
+    lower := actions[best].Mean - z * actions[best].StdErr
+
+    upper := math.Inf(-1)
+    for i, action := range actions {
+        if i != best {
+            upper = math.Max(upper, action.Mean + z * action.StdErr)
+        }
+    }
+
+    return lower > upper
+}
@@ -0,0 +1,118 @@
+package ai
+
+import "testing"
+
+
+/*
+ * A test that defines the root actions during a search, the width of the
+ * confidence bounds, the visits every action needs and whether the best action
+ * is separated from the rest.
+ */
+type separatedTest struct {
+    actions []ActionStats
+    z float64
+    minVisits int
+    expected bool
+}
+
+
+var separatedTests = []separatedTest {
+    /*
+     * A single action is always separated.
+     */
+    separatedTest {
+        []ActionStats {
+            ActionStats { Visits: 1, Mean: -2 },
+        },
+        2,
+        10,
+        true,
+    },
+
+    /*
+     * The best lower bound of 1.5 beats the other upper bound of 0.4.
+     */
+    separatedTest {
+        []ActionStats {
+            ActionStats { Visits: 100, Mean: 0, StdErr: 0.2 },
+            ActionStats { Visits: 100, Mean: 2, StdErr: 0.25 },
+        },
+        2,
+        10,
+        true,
+    },
+
+    /*
+     * The bounds of the two actions overlap.
+     */
+    separatedTest {
+        []ActionStats {
+            ActionStats { Visits: 100, Mean: 0, StdErr: 0.2 },
+            ActionStats { Visits: 100, Mean: 2, StdErr: 0.25 },
+            ActionStats { Visits: 100, Mean: 1.2, StdErr: 0.3 },
+        },
+        2,
+        10,
+        false,
+    },
+
+    /*
+     * Narrower bounds separate the same actions.
+     */
+    separatedTest {
+        []ActionStats {
+            ActionStats { Visits: 100, Mean: 0, StdErr: 0.2 },
+            ActionStats { Visits: 100, Mean: 2, StdErr: 0.25 },
+            ActionStats { Visits: 100, Mean: 1.2, StdErr: 0.3 },
+        },
+        1,
+        10,
+        true,
+    },
+
+    /*
+     * The bounds are not trusted until every action has enough visits.
+     */
+    separatedTest {
+        []ActionStats {
+            ActionStats { Visits: 5, Mean: -4 },
+            ActionStats { Visits: 100, Mean: 2, StdErr: 0.25 },
+        },
+        2,
+        10,
+        false,
+    },
+}
+
+
+/*
+ * The main driver for the separation tests outlined above.
+ */
+func TestSeparated(t *testing.T) {
+    for i, fixture := range separatedTests {
+        res := Separated(fixture.actions, fixture.z, fixture.minVisits)
+        if res != fixture.expected {
+            t.Errorf("Fixture %d gave %t instead of %t.\n", i + 1, res,
+                     fixture.expected)
+        }
+    }
+}
+
+
+/*
+ * Tests that the limit and interval of a search are the budget unless early
+ * stopping is on.
+ */
+func TestEarlyStopBudget(t *testing.T) {
+    off := EarlyStop{ Interval: 10, Extend: 2 }
+    if off.limit(100) != 100 || off.interval(100) != 100 {
+        t.Errorf("Without Z gave a limit of %d and an interval of %d.\n",
+                 off.limit(100), off.interval(100))
+    }
+
+    on := EarlyStop{ Z: 2, Interval: 10, Extend: 1.5 }
+    if on.limit(100) != 150 || on.interval(100) != 10 {
+        t.Errorf("With Z gave a limit of %d and an interval of %d.\n",
+                 on.limit(100), on.interval(100))
+    }
+}
@@ -0,0 +1,102 @@
+package ai
+
+
+/*
+ * A cutoff decides when a search stops short of the end of the game and scores
+ * the state it has reached with a heuristic instead. This trades accuracy for
+ * speed when there is little time for a decision.
+ */
+type Cutoff interface {
+    /*
+     * Whether the search should stop at a state.
+     *
+     * Args:
+     *  root: The state the search started from.
+     *  state: The state that has been reached. It is not terminal.
+     *  depth: The number of moves from root to state.
+     *
+     * Returns:
+     *  True if state should be scored with the heuristic.
+     */
+    Stop(root, state TSState, depth int) bool
+}
+
+
+/*
+ * A cutoff that stops once Depth moves have been made from the root of the
+ * search.
+ */
+type DepthCutoff struct {
+    Depth int
+}
+
+
+func (c DepthCutoff) Stop(root, state TSState, depth int) bool {
+    return depth >= c.Depth
+}
+
+
+/*
+ * An engine that can score a state that is not terminal. The score is an
+ * estimate of Evaluation at the end of the game, from the same point of view,
+ * and should be the same as Evaluation for a terminal state.
+ */
+type HeuristicEngine interface {
+    Heuristic(state TSState) float64
+}
+
+
+/*
+ * The cutoff of one search, along with what it needs to score a state. The zero
+ * value never stops.
+ */
+type searchCutoff struct {
+    cutoff Cutoff
+    root TSState
+    heuristic func(state TSState) float64
+}
+
+
+/*
+ * Find the cutoff for a search. The heuristic is the one given, or if that is
+ * nil, the engine's own if it is a HeuristicEngine.
+ *
+ * Args:
+ *  root: The state the search starts from.
+ *  engine: The engine for the game logic.
+ *  cutoff: The cutoff of the search, or nil for none.
+ *  heuristic: The heuristic to score states with, or nil for the engine's.
+ *
+ * Returns:
+ *  The cutoff of the search. It never stops if there is no cutoff or no
+ *  heuristic to score states with.
+ */
+func newSearchCutoff(root TSState, engine TSEngine, cutoff Cutoff,
+                     heuristic func(state TSState) float64) searchCutoff {
+    if heuristic == nil {
+        if h, ok := engine.(HeuristicEngine); ok {
+            heuristic = h.Heuristic
+        }
+    }
+
+    if cutoff == nil || heuristic == nil {
+        return searchCutoff{ }
+    }
+
+    return searchCutoff{ cutoff, root, heuristic }
+}
+
+
+/*
+ * Whether the search should stop at a state and score it with the heuristic.
+ *
+ * Args:
+ *  state: The state that has been reached. It is not terminal.
+ *  depth: The number of moves from the root to the state.
+ *
+ * Returns:
+ *  True if the state should be scored with the heuristic.
+ */
+func (c searchCutoff) stop(state TSState, depth int) bool {
+    return c.cutoff != nil && c.cutoff.Stop(c.root, state, depth)
+}
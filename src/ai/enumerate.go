@@ -0,0 +1,84 @@
+package ai
+
+
+/*
+ * A determinization of a state along with how likely it is to be the true
+ * state, relative to the other determinizations it was given with.
+ */
+type Determinization struct {
+    State State
+    Weight float64
+}
+
+
+/*
+ * A state that can list every determinization that is consistent with it, as
+ * when few enough hidden cards are left. Searches use the list in place of
+ * random determinizations when it is short enough, so that they average over
+ * the true set of worlds rather than a sample of it.
+ */
+type EnumerableState interface {
+    State
+
+    /*
+     * List every determinization of the state.
+     *
+     * Args:
+     *  max: The most determinizations to list.
+     *
+     * Returns:
+     *  Every determinization with weights that sum to 1, or nil if there are
+     *  more than max of them.
+     */
+    Determinizations(max int) []Determinization
+}
+
+
+/*
+ * List every determinization of a state if it can be done within the given
+ * number.
+ *
+ * Args:
+ *  s: The state to determinize.
+ *  max: The most determinizations to list.
+ *
+ * Returns:
+ *  Every determinization of s, or nil if s is not an EnumerableState or has
+ *  more than max determinizations.
+ */
+func Enumerate(s State, max int) []Determinization {
+    if e, ok := s.(EnumerableState); ok && max > 0 {
+        return e.Determinizations(max)
+    }
+
+    return nil
+}
+
+
+/*
+ * The worlds a search over a fixed number of determinizations should use. If
+ * the state has no more determinizations than the number asked for, every one
+ * of them is used with its weight. Otherwise the state is determinized the
+ * given number of times, and each world has the same weight.
+ *
+ * Args:
+ *  s: The state to determinize.
+ *  deters: The number of determinizations to draw.
+ *
+ * Returns:
+ *  The worlds to search, with weights that sum to 1.
+ */
+func Worlds(s State, deters int) []Determinization {
+    if deals := Enumerate(s, deters); deals != nil {
+        return deals
+    }
+
+    sampled := make([]Determinization, deters)
+    for i := range sampled {
+        d := s.Copy()
+        d.Determinize()
+        sampled[i] = Determinization{ d, 1 / float64(deters) }
+    }
+
+    return sampled
+}
@@ -0,0 +1,190 @@
+package ai
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "math"
+    "sort"
+)
+
+
+/*
+ * The limits on how much of a tree is exported. Only nodes at most MaxDepth
+ * moves below the exported root are written, and only if they were visited at
+ * least MinVisits times. A MaxDepth of 0 means there is no depth limit.
+ */
+type ExportLimits struct {
+    MaxDepth int
+    MinVisits int
+}
+
+
+/*
+ * A node of an exported tree. This is the form the tree takes in JSON. Action
+ * is the action that leads to the node as text, or root for the root. UCB is
+ * the UCB1 value of the node with sqrt(2) as the bias parameter, and is 0 for
+ * the root.
+ */
+type ExportedNode struct {
+    Action string
+    Visits int
+    Mean float64
+    UCB float64
+    Children []*ExportedNode
+}
+
+
+/*
+ * Convert an MCTS tree to its exported form. The children of each node are
+ * ordered from the most visited to the least visited.
+ *
+ * Args:
+ *  root: The root of the tree to export.
+ *  limits: The limits on the nodes to include. The root is always included.
+ *
+ * Returns:
+ *  The exported tree.
+ */
+func ExportTree(root *Node, limits ExportLimits) *ExportedNode {
+    return exportNode(root, 0, limits)
+}
+
+
+/*
+ * Write an MCTS tree as JSON.
+ *
+ * Args:
+ *  w: Where to write the tree.
+ *  root: The root of the tree to write.
+ *  limits: The limits on the nodes to include.
+ *
+ * Returns:
+ *  Any error from encoding or writing the tree.
+ */
+func WriteJSON(w io.Writer, root *Node, limits ExportLimits) error {
+    encoder := json.NewEncoder(w)
+    encoder.SetIndent("", "  ")
+
+    return encoder.Encode(ExportTree(root, limits))
+}
+
+
+/*
+ * Write an MCTS tree as a Graphviz DOT digraph, which can be rendered with
+ * something like dot -Tsvg. Every node is labelled with its action, visits,
+ * mean reward and UCB.
+ *
+ * Args:
+ *  w: Where to write the tree.
+ *  root: The root of the tree to write.
+ *  limits: The limits on the nodes to include.
+ *
+ * Returns:
+ *  Any error from writing the tree.
+ */
+func WriteDOT(w io.Writer, root *Node, limits ExportLimits) error {
+    if _, err := fmt.Fprintln(w, "digraph mcts {"); err != nil {
+        return err
+    }
+
+    id := 0
+    if err := writeDOTNode(w, ExportTree(root, limits), &id); err != nil {
+        return err
+    }
+
+    _, err := fmt.Fprintln(w, "}")
+    return err
+}
+
+
+/*
+ * Write a node and everything below it as DOT statements.
+ *
+ * Args:
+ *  w: Where to write the statements.
+ *  node: The exported node to write.
+ *  id: The next unused node id, which is advanced past every written node.
+ *
+ * Returns:
+ *  The id of the written node and any error from writing.
+ */
+func writeDOTNode(w io.Writer, node *ExportedNode, id *int) error {
+    own := *id
+    *id++
+
+    label := fmt.Sprintf("%s\nvisits: %d\nmean: %.3f\nucb: %.3f", node.Action,
+                         node.Visits, node.Mean, node.UCB)
+    if _, err := fmt.Fprintf(w, "    n%d [label=%q];\n", own, label); err != nil {
+        return err
+    }
+
+    for _, child := range node.Children {
+        childId := *id
+        if err := writeDOTNode(w, child, id); err != nil {
+            return err
+        }
+
+        if _, err := fmt.Fprintf(w, "    n%d -> n%d;\n", own, childId); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+
+/*
+ * The recursive part of ExportTree.
+ *
+ * Args:
+ *  node: The node to export.
+ *  depth: How many moves the node is below the exported root.
+ *  limits: The limits on the nodes to include.
+ *
+ * Returns:
+ *  The exported node with its children that are within the limits.
+ */
+func exportNode(node *Node, depth int, limits ExportLimits) *ExportedNode {
+    action := "root"
+    ucb := 0.0
+    if depth > 0 {
+        action = fmt.Sprint(node.GetMove().Action)
+        ucb = UpperConfBound(node)
+    }
+
+    // JSON has no infinity, so a node that was never visited shows 0.
+    if math.IsInf(ucb, 0) {
+        ucb = 0
+    }
+
+    exported := &ExportedNode {
+        Action: action,
+        Visits: node.simulations,
+        Mean: node.Mean(),
+        UCB: ucb,
+        Children: make([]*ExportedNode, 0),
+    }
+
+    if limits.MaxDepth > 0 && depth >= limits.MaxDepth {
+        return exported
+    }
+
+    children := make([]*Node, 0, len(node.children))
+    for i := range node.children {
+        child := &node.children[i]
+        if child.simulations >= limits.MinVisits {
+            children = append(children, child)
+        }
+    }
+    sort.SliceStable(children, func(i, j int) bool {
+        return children[i].simulations > children[j].simulations
+    })
+
+    for _, child := range children {
+        exported.Children = append(exported.Children,
+                                   exportNode(child, depth + 1, limits))
+    }
+
+    return exported
+}
@@ -0,0 +1,93 @@
+package ai
+
+import (
+    "bytes"
+    "encoding/json"
+    "strings"
+    "testing"
+)
+
+
+/*
+ * Build a small tree by hand. The root has been visited 10 times, with one
+ * child visited 7 times that has a child of its own, and another child visited
+ * 3 times.
+ */
+func exportTestTree() *Node {
+    root := NewNode()
+    root.simulations = 10
+
+    counts := []int{ 3, 7 }
+    for i, count := range counts {
+        child := root.addChild(Move{ i, nil }, len(counts))
+        child.simulations = count
+        child.eval = float64(count)
+    }
+
+    grandchild := root.children[1].addChild(Move{ "deep", nil }, 1)
+    grandchild.simulations = 2
+
+    return root
+}
+
+
+/*
+ * Test that the export limits drop deep and rarely visited nodes, and that
+ * children are ordered by visits.
+ */
+func TestExportTree(t *testing.T) {
+    root := exportTestTree()
+
+    full := ExportTree(root, ExportLimits{ })
+    if len(full.Children) != 2 || full.Children[0].Visits != 7 ||
+       len(full.Children[0].Children) != 1 {
+        t.Errorf("The full tree has the wrong shape.\n")
+    }
+
+    if full.Children[0].Mean != 1 || full.Children[0].UCB <= 1 {
+        t.Errorf("Gave a mean of %f and UCB of %f.\n", full.Children[0].Mean,
+                 full.Children[0].UCB)
+    }
+
+    shallow := ExportTree(root, ExportLimits{ MaxDepth: 1 })
+    if len(shallow.Children[0].Children) != 0 {
+        t.Errorf("The depth limit was not kept.\n")
+    }
+
+    visited := ExportTree(root, ExportLimits{ MinVisits: 3 })
+    if len(visited.Children) != 2 || len(visited.Children[0].Children) != 0 {
+        t.Errorf("The visit limit was not kept.\n")
+    }
+}
+
+
+/*
+ * Test that the tree can be written as JSON that reads back, and as DOT.
+ */
+func TestWriteTree(t *testing.T) {
+    root := exportTestTree()
+
+    var buf bytes.Buffer
+    if err := WriteJSON(&buf, root, ExportLimits{ }); err != nil {
+        t.Fatal(err)
+    }
+
+    var read ExportedNode
+    if err := json.Unmarshal(buf.Bytes(), &read); err != nil {
+        t.Fatal(err)
+    }
+    if read.Visits != 10 || read.Children[0].Children[0].Action != "deep" {
+        t.Errorf("The JSON tree did not read back.\n")
+    }
+
+    buf.Reset()
+    if err := WriteDOT(&buf, root, ExportLimits{ }); err != nil {
+        t.Fatal(err)
+    }
+
+    dot := buf.String()
+    if !strings.HasPrefix(dot, "digraph mcts {") ||
+       strings.Count(dot, "->") != 3 || !strings.Contains(dot, "visits: 7") {
+        t.Errorf("Gave the DOT output:\n%s", dot)
+    }
+}
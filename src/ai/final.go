@@ -0,0 +1,85 @@
+package ai
+
+import "math"
+
+
+/*
+ * How MCTS picks the move to make once the search is done.
+ *
+ * MaxVisits picks the root action that was visited the most, which is the
+ * most robust since the tree policy visits what it thinks is best. MaxMean
+ * picks the action with the highest mean reward, which can be fooled by an
+ * action that got lucky in a few visits. RobustMax picks the action that has
+ * both the most visits and the highest mean. If no action has both, the search
+ * can not be run for longer, so the action with the highest mean less one
+ * standard error is picked instead.
+ */
+type FinalSelection int
+const (
+    MaxVisits FinalSelection = iota
+    MaxMean
+    RobustMax
+)
+
+
+/*
+ * Pick the final action with the given strategy. Ties go to the earliest
+ * action.
+ *
+ * Args:
+ *  actions: The statistics of every root action. There must be at least one.
+ *  final: The strategy to pick with.
+ *
+ * Returns:
+ *  The index of the picked action in actions.
+ */
+func SelectFinal(actions []ActionStats, final FinalSelection) int {
+    byVisits := argmaxAction(actions, func(a ActionStats) float64 {
+        return float64(a.Visits)
+    })
+    byMean := argmaxAction(actions, func(a ActionStats) float64 {
+        return a.Mean
+    })
+
+    switch final {
+    case MaxMean:
+        return byMean
+    case RobustMax:
+        if actions[byVisits].Mean == actions[byMean].Mean {
+            return byVisits
+        } else if actions[byMean].Visits == actions[byVisits].Visits {
+            return byMean
+        }
+
+        return argmaxAction(actions, func(a ActionStats) float64 {
+            return a.Mean - a.StdErr
+        })
+    }
+
+    return byVisits
+}
+
+
+/*
+ * Find the action with the highest score. Ties go to the first such action.
+ *
+ * Args:
+ *  actions: The statistics of each action.
+ *  score: The scoring function for an action.
+ *
+ * Returns:
+ *  The index of the action with the highest score.
+ */
+func argmaxAction(actions []ActionStats, score func(ActionStats) float64) int {
+    best := 0
+    bestScore := math.Inf(-1)
+    for i, a := range actions {
+        s := score(a)
+        if i == 0 || s > bestScore {
+            best = i
+            bestScore = s
+        }
+    }
+
+    return best
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+    "math"
+    "testing"
+)
+
+
+/*
+ * A test that defines the root actions after a search, the final selection
+ * strategy and the action that should be picked.
+ */
+type finalTest struct {
+    final FinalSelection
+    actions []ActionStats
+    expected int
+}
+
+
+var finalTests = []finalTest {
+    /*
+     * Max visits ignores a lucky action with few visits.
+     */
+    finalTest {
+        MaxVisits,
+        []ActionStats {
+            ActionStats { Visits: 5, Mean: 2 },
+            ActionStats { Visits: 95, Mean: 1 },
+        },
+        1,
+    },
+
+    /*
+     * Max mean picks the lucky action.
+     */
+    finalTest {
+        MaxMean,
+        []ActionStats {
+            ActionStats { Visits: 5, Mean: 2 },
+            ActionStats { Visits: 95, Mean: 1 },
+        },
+        0,
+    },
+
+    /*
+     * Robust max picks the action that is best in both ways.
+     */
+    finalTest {
+        RobustMax,
+        []ActionStats {
+            ActionStats { Visits: 5, Mean: 0.5 },
+            ActionStats { Visits: 95, Mean: 1 },
+        },
+        1,
+    },
+
+    /*
+     * Without such an action, robust max picks the best lower bound.
+     */
+    finalTest {
+        RobustMax,
+        []ActionStats {
+            ActionStats { Visits: 5, Mean: 2, StdErr: 1.5 },
+            ActionStats { Visits: 95, Mean: 1, StdErr: 0.1 },
+            ActionStats { Visits: 50, Mean: 1.5, StdErr: 0.2 },
+        },
+        2,
+    },
+}
+
+
+/*
+ * The main driver for the final selection tests outlined above.
+ */
+func TestSelectFinal(t *testing.T) {
+    for i, fixture := range finalTests {
+        chosen := SelectFinal(fixture.actions, fixture.final)
+        if chosen != fixture.expected {
+            t.Errorf("Fixture %d gave %d instead of %d.\n", i + 1, chosen,
+                     fixture.expected)
+        }
+    }
+}
+
+
+/*
+ * Test that the totals of an action give its true mean and standard error.
+ */
+func TestActionTotals(t *testing.T) {
+    var totals actionTotals
+    // Rewards of 1, 1, -1 and 3 from two trees.
+    totals.add(Move{ }, 3, 1, 3, -1, 1)
+    totals.add(Move{ }, 1, 3, 9, 3, 3)
+
+    stats := totals.stats()
+    if stats.Visits != 4 || stats.Mean != 1 {
+        t.Errorf("Gave %d visits and a mean of %f instead of 4 and 1.\n",
+                 stats.Visits, stats.Mean)
+    }
+
+    // The variance is 3 - 1 = 2, so the standard error is sqrt(2 / 4).
+    if math.Abs(stats.StdErr - math.Sqrt(0.5)) > 1e-9 {
+        t.Errorf("Gave a standard error of %f instead of %f.\n", stats.StdErr,
+                 math.Sqrt(0.5))
+    }
+
+    if stats.Min != -1 || stats.Max != 3 {
+        t.Errorf("Gave a range of [%f, %f] instead of [-1, 3].\n", stats.Min,
+                 stats.Max)
+    }
+}
@@ -0,0 +1,294 @@
+package ai
+
+import (
+    "math"
+    "rng"
+)
+
+
+/*
+ * A node in the Information Set MCTS tree. Unlike the per determinization tree
+ * of MCTS, a single tree is shared by every determinization. A node stands for
+ * the information set reached by the sequence of actions from the root, which
+ * all players can observe. Since a given determinization may not allow every
+ * action, each node tracks how many times it was available for selection in
+ * addition to how many times it was selected.
+ */
+type ISNode struct {
+    action interface{}
+    parent *ISNode
+    children []*ISNode
+
+    eval float64
+    sumSquares float64
+    min float64
+    max float64
+    simulations int
+    availability int
+    prior float64
+}
+
+
+/*
+ * Return a new root node for an Information Set MCTS tree.
+ *
+ * Returns:
+ *  A node with no action, parent or children.
+ */
+func NewISNode() *ISNode {
+    return &ISNode{ }
+}
+
+
+/*
+ * The action that leads from the parent information set to this one.
+ *
+ * Returns:
+ *  The action of this node. This is nil for the root.
+ */
+func (node *ISNode) GetAction() interface{} {
+    return node.action
+}
+
+
+/*
+ * The number of times this node has been selected and played through.
+ *
+ * Returns:
+ *  The visit count of the node.
+ */
+func (node *ISNode) GetSimulations() int {
+    return node.simulations
+}
+
+
+/*
+ * The average evaluation of this node from the point of view of the player who
+ * chose the action leading to it.
+ *
+ * Returns:
+ *  The mean reward of the node or 0 if it has not been visited.
+ */
+func (node *ISNode) Mean() float64 {
+    if node.simulations == 0 {
+        return 0
+    }
+
+    return node.eval / float64(node.simulations)
+}
+
+
+/*
+ * The variance of the evaluations of this node.
+ *
+ * Returns:
+ *  The variance of the node's rewards or 0 if it has not been visited.
+ */
+func (node *ISNode) Variance() float64 {
+    if node.simulations == 0 {
+        return 0
+    }
+
+    mean := node.Mean()
+    return math.Max(0, node.sumSquares / float64(node.simulations) - mean * mean)
+}
+
+
+/*
+ * Performs a single observer Information Set Monte Carlo Tree Search. There is
+ * one tree for the whole search, and on every iteration the given state is
+ * determinized anew and played down the tree. Only the children whose actions
+ * are legal in the current determinization are considered, so statistics are
+ * shared by every world in which an action is possible. This avoids the
+ * strategy fusion of summing separate determinized trees.
+ *
+ * Args:
+ *  s: The current state from which to start simulation. This is the state
+ *     from the point of view of the searching player and should not be
+ *     terminal.
+ *  engine: The game engine with which to step through game logic.
+ *  iterations: The number of determinizations and playouts to run.
+ *
+ * Returns:
+ *  The move that was visited the most from the root and its mean value from
+ *  the point of view of the player to move. The state of the returned move is
+ *  the successor from the last determinization the action was seen in.
+ */
+func ISMCTS(s State, engine TSEngine, iterations int) (Move, float64) {
+    return ISMCTSWithOptions(s, engine, iterations, DefaultMCTSOptions())
+}
+
+
+/*
+ * Performs an Information Set MCTS in the same way as ISMCTS, but with the
+ * given options instead of the defaults. The tree policy is given how many
+ * times each child was available in place of the parent's visit count, so that
+ * actions that are only legal in a few determinizations do not look under
+ * explored and get selected far too often.
+ *
+ * Args:
+ *  s: The current state from which to start simulation.
+ *  engine: The game engine with which to step through game logic.
+ *  iterations: The number of determinizations and playouts to run.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The move picked by the final selection of the options and its mean value
+ *  from the point of view of the player to move.
+ */
+func ISMCTSWithOptions(s State, engine TSEngine, iterations int,
+                       opts MCTSOptions) (Move, float64) {
+    return ISMCTSReport(s, engine, iterations, opts).Best()
+}
+
+
+/*
+ * Performs an Information Set MCTS as in ISMCTSWithOptions, but gives a full
+ * report with the statistics of every root action rather than just the chosen
+ * move.
+ *
+ * Args:
+ *  s: The current state from which to start simulation.
+ *  engine: The game engine with which to step through game logic.
+ *  iterations: The number of determinizations and playouts to run.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The report of the search. The actions are in the order they were added to
+ *  the tree.
+ */
+func ISMCTSReport(s State, engine TSEngine, iterations int,
+                  opts MCTSOptions) Report {
+    return NewISMCTSTree().Report(s, engine, iterations, opts)
+}
+
+
+/*
+ * Run one iteration of Information Set MCTS from the given node using a
+ * determinized state that is consistent with the node's information set. The
+ * tree is descended with availability aware UCB1, one node is added, the rest
+ * of the game is played out by the rollout policy, and the result is
+ * backpropagated.
+ *
+ * Args:
+ *  root: The node to start the iteration from.
+ *  state: A determinized state for the information set of root.
+ *  engine: The engine for traversing through the game tree.
+ *
+ * Returns:
+ *  The final evaluation of the playout per the engine's computation.
+ */
+func RunISPlayout(root *ISNode, state TSState, engine TSEngine) float64 {
+    return runISPlayout(root, state, engine, DefaultMCTSOptions())
+}
+
+
+/*
+ * The internal logic of an ISMCTS iteration with the tree policy and priors of
+ * the given options.
+ *
+ * Args:
+ *  root: The node to start the iteration from.
+ *  state: A determinized state for the information set of root.
+ *  engine: The engine for traversing through the game tree.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The final evaluation of the playout per the engine's computation.
+ */
+func runISPlayout(root *ISNode, state TSState, engine TSEngine,
+                  opts MCTSOptions) float64 {
+    node := root
+    path := make([]*ISNode, 0)
+    favs := make([]bool, 0)
+    cut := newSearchCutoff(state, engine, opts.Cutoff, opts.Heuristic)
+
+    // Selection and expansion. Descend while every legal action in this
+    // determinization already has a node, and stop once a node is added.
+    for !engine.IsTerminal(state) && !cut.stop(state, len(path)) {
+        moves := engine.Successors(state)
+        fav := engine.Favorable(state)
+
+        children := make(map[interface{}]*ISNode)
+        for _, child := range node.children {
+            children[child.action] = child
+        }
+
+        untried := make([]Move, 0)
+        for _, move := range moves {
+            if _, ok := children[move.Action]; !ok {
+                untried = append(untried, move)
+            }
+        }
+
+        if len(untried) > 0 {
+            move := untried[rng.Intn(len(untried))]
+            next := &ISNode {
+                action: move.Action,
+                parent: node,
+                prior: movePrior(state, moves, move, opts),
+            }
+            node.children = append(node.children, next)
+
+            // The new node was available this time as well as all of its
+            // siblings that are legal here.
+            for _, m := range moves {
+                if child, ok := children[m.Action]; ok {
+                    child.availability++
+                }
+            }
+            next.availability++
+
+            node = next
+            state = move.State
+            path = append(path, node)
+            favs = append(favs, fav)
+            break
+        }
+
+        stats := make([]ChildStats, len(moves))
+        for i, move := range moves {
+            child := children[move.Action]
+            child.availability++
+
+            stats[i] = ChildStats {
+                Visits: child.simulations,
+                ParentVisits: child.availability,
+                Mean: child.Mean(),
+                Variance: child.Variance(),
+                Prior: child.prior,
+            }
+        }
+
+        chosen := moves[opts.Policy.Select(stats)]
+        node = children[chosen.Action]
+        state = chosen.State
+        path = append(path, node)
+        favs = append(favs, fav)
+    }
+
+    // Simulation. The rest of the game is played out by the rollout policy.
+    eval := rollout(state, engine, opts.Rollout, false, nil, cut, len(path))
+
+    // Backpropagation. Each node is credited from the point of view of the
+    // player who chose to move into it.
+    root.simulations++
+    for i, n := range path {
+        signed := eval
+        if !favs[i] {
+            signed = -eval
+        }
+
+        n.simulations++
+        n.sumSquares += eval * eval
+        n.eval += signed
+        if n.simulations == 1 || signed < n.min {
+            n.min = signed
+        }
+        if n.simulations == 1 || signed > n.max {
+            n.max = signed
+        }
+    }
+
+    return eval
+}
@@ -1,15 +1,11 @@
 package ai
 
 import (
-    "container/heap"
     "fmt"
     "math"
-    "math/rand"
-    "time"
+    "rng"
 )
 
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 type State interface {
     Determinize()
     // TODO: Is there a more efficient way than copying to get new
@@ -18,34 +14,100 @@ type State interface {
 }
 
 // This is a Node that is used for the MCTS tree. It has the attributes necessary
-// for this role such as, parent, children, wins, and simulations but also
-// implements methods from PQItem, since the list of node children is a priority
-// queue based on the node's UCB. Any data can be passed along with a node
-// through the Value methods, which accept a blank interface type.
+// for this role such as, parent, children, wins, and simulations. Nodes live in
+// the arena of their tree, and the children of a node are a compact run of
+// nodes in that arena with room for every move from the node's state. Children
+// are not kept in any order, since the tree policy scores them when it
+// selects. Any data can be passed along with a node through the Value methods,
+// which accept a blank interface type.
 type Node struct {
     value Move
-    priority float64
-    index int
 
-    children PriorityQueue
+    children []Node
     parent *Node
+    arena *arena
 
     eval float64
+    sumSquares float64
+    min float64
+    max float64
     simulations int
+    prior float64
+
+    amafEval float64
+    amafSimulations int
 
     memoize []Move
     depth int
 }
 
-// Return a new node that is properly initialized. Specifically, the priority
-// queue for the children is properly made.
-func NewNode() *Node {
-    var n Node
 
-    n.children = make(PriorityQueue, 0)
-    heap.Init(&n.children)
+/*
+ * The options that control how MCTS searches. The zero value is not valid, so
+ * start from DefaultMCTSOptions and change what is needed.
+ *
+ * Policy is the tree policy that chooses which child to descend to once all
+ * children of a node are expanded. Priors gives the prior probability of each
+ * move from a state for policies like PUCT that use them. If it is nil, every
+ * move gets the same prior. Rollout is the policy that plays out the rest of
+ * the game once a new node has been added to the tree. AMAF turns on the
+ * tracking of All-Moves-As-First statistics, which the RAVE policy blends with
+ * the normal ones. ISMCTS does not track AMAF statistics. Final is how the move
+ * to make is picked from the root once the search is done.
+ *
+ * MaxNodes caps the number of nodes in each MCTS tree, which bounds its memory
+ * at roughly 800 bytes a node for euchre. Room for all of a node's children is
+ * taken when the first of them is added. If it is 0 there is no cap. Once a
+ * tree is full, a node with no children is not expanded and is played out from
+ * instead, though the root is always expanded. If Recycle is set, a tree
+ * instead has its least visited subtrees cut off before an expansion could take
+ * it over the cap, until it is at three quarters of the cap or less, so that
+ * the search can keep growing where it matters. ISMCTS does not have a cap.
+ *
+ * Cutoff stops a playout, in the tree or in the rollout, before the end of the
+ * game, and the state it stopped at is scored by Heuristic instead of being
+ * played out. If Heuristic is nil, the engine's own is used if it is a
+ * HeuristicEngine. If Cutoff is nil, or there is no heuristic, every playout
+ * goes to the end of the game.
+ *
+ * Stop lets a search end before its budget is spent when one root action is
+ * clearly best, and run past it when the decision is close. The zero value
+ * spends exactly the budget.
+ */
+type MCTSOptions struct {
+    Policy SelectionPolicy
+    Priors func(state TSState, moves []Move) []float64
+    Rollout RolloutPolicy
+    AMAF bool
+    Final FinalSelection
+    MaxNodes int
+    Recycle bool
+    Cutoff Cutoff
+    Heuristic func(state TSState) float64
+    Stop EarlyStop
+}
+
 
-    return &n
+/*
+ * The options that MCTS uses when none are given. This is UCB1 with sqrt(2) as
+ * the exploration constant, uniform priors, uniformly random rollouts and the
+ * most visited move as the final move.
+ *
+ * Returns:
+ *  The default MCTS options.
+ */
+func DefaultMCTSOptions() MCTSOptions {
+    return MCTSOptions {
+        Policy: UCB1{ math.Sqrt2 },
+        Rollout: RandomRollout{ },
+        Final: MaxVisits,
+    }
+}
+
+// Return a new node that is properly initialized. Specifically, the node is
+// the root of a new tree with its own arena.
+func NewNode() *Node {
+    return &Node{ arena: &arena{ } }
 }
 
 func (node *Node) GetMove() Move {
@@ -64,23 +126,82 @@ func (node *Node) Value(v interface{}) {
     node.value = v.(Move)
 }
 
-// The priority of a node is based on how many times all of its siblings have
-// been sampled, how many times it has been sampled, and how many times it and
-// its siblings have been sampled.
-func (node *Node) GetPriority() float64 {
-    return node.priority
+// Add a child to the node for the given move. The first child that is added
+// reserves room in the arena for all of the node's children, so the number of
+// moves from the node's state must be given, and can not change later.
+func (node *Node) addChild(move Move, moves int) *Node {
+    if node.children == nil {
+        node.children = node.arena.reserve(moves)
+    }
+
+    node.children = node.children[:len(node.children) + 1]
+    child := &node.children[len(node.children) - 1]
+    child.value = move
+    child.parent = node
+    child.arena = node.arena
+    child.depth = node.depth + 1
+
+    return child
+}
+
+// The number of nodes that the tree this node is in takes up, which includes
+// the room for children that have not been added yet.
+func (node *Node) TreeSize() int {
+    return node.arena.used
+}
+
+// The number of times the tree this node is in has been recycled to stay under
+// its cap on nodes.
+func (node *Node) Recycles() int {
+    return node.arena.recycles
+}
+
+// The number of times this node has been played through.
+func (node *Node) GetSimulations() int {
+    return node.simulations
+}
+
+// The average evaluation of this node from the point of view of the player who
+// chose the move leading to it, or 0 if it has not been played through.
+func (node *Node) Mean() float64 {
+    if node.simulations == 0 {
+        return 0
+    }
+
+    return node.eval / float64(node.simulations)
+}
+
+// The variance of the evaluations of this node, or 0 if it has not been played
+// through.
+func (node *Node) Variance() float64 {
+    if node.simulations == 0 {
+        return 0
+    }
+
+    mean := node.Mean()
+    return math.Max(0, node.sumSquares / float64(node.simulations) - mean * mean)
 }
 
-func (node *Node) Priority(priority float64) {
-    node.priority = priority
+// The prior probability of the move leading to this node, as given when the
+// node was expanded.
+func (node *Node) GetPrior() float64 {
+    return node.prior
 }
 
-func (node *Node) GetIndex() int {
-    return node.index
+// The number of playouts through the parent in which this node's action was
+// played later by the same side, which are its All-Moves-As-First visits.
+func (node *Node) GetAmafSimulations() int {
+    return node.amafSimulations
 }
 
-func (node *Node) Index(i int) {
-    node.index = i
+// The average evaluation of the AMAF playouts of this node, or 0 if there are
+// none.
+func (node *Node) AmafMean() float64 {
+    if node.amafSimulations == 0 {
+        return 0
+    }
+
+    return node.amafEval / float64(node.amafSimulations)
 }
 
 // The UpperConfBound for a node's expected winnings are based on the node's
@@ -88,7 +209,8 @@ func (node *Node) Index(i int) {
 // its siblings have been played. This upper confidence bound is a tradeoff
 // between exploitation and explortion. As we exploit one node more and more
 // that we think is a good choice, it's confidence bound narrows, and we become
-// more curious of other nodes.
+// more curious of other nodes. This is the UCB1 formula, and the tree policy
+// used for selection is configured separately through MCTSOptions.
 // node - The node which we are calculating the UCB of.
 // Returns the UCB of the node using sqrt(2) as the bias parameter.
 func UpperConfBound(node *Node) float64 {
@@ -96,8 +218,8 @@ func UpperConfBound(node *Node) float64 {
     if node.simulations == 0 && node.parent != nil {
         ucb = math.Inf(1)
     } else if node.parent != nil {
-        ucb = float64(node.eval) / float64(node.simulations) +
-              math.Sqrt(2.0 * (math.Log(float64(node.parent.simulations)) + 1) / float64(node.simulations))
+        ucb = node.Mean() +
+              math.Sqrt(2.0 * math.Log(float64(node.parent.simulations)) / float64(node.simulations))
     }
     return ucb
 }
@@ -116,49 +238,78 @@ func UpperConfBound(node *Node) float64 {
  *  deters: The number of determinizations to run through.
  *
  * Returns:
- *  The next state with the highest value and the expected value associated with
- *  it.
+ *  The most visited move from the root and its mean reward from the point of
+ *  view of the player to move.
  */
 func MCTS(s State, engine TSEngine, runs int, deters int) (Move, float64) {
-    // TODO: Is there a better way than this dual map way. This probably isn't
-    // a bottleneck however.
-    weights := make(map[interface{}]float64)
-    conv := make(map[interface{}]Move)
-    counts := make(map[interface{}]int)
-
-    for i := 0; i < deters; i++ {
-        copyState := s.Copy()
-        copyState.Determinize()
-
-        n := NewNode()
-        m := Move {
-            nil,
-            copyState,
-        }
-        n.Value(m)
+    return MCTSWithOptions(s, engine, runs, deters, DefaultMCTSOptions())
+}
 
-        for j := 0; j < runs; j++ {
-            RunPlayout(n, engine)
 
-            topNode := n.children.Poll().(*Node)
-            topMove := topNode.GetMove()
+/*
+ * Performs a Monte Carlo Tree search in the same way as MCTS, but with the
+ * given options, such as the tree policy, instead of the defaults.
+ *
+ * Args:
+ *  s: The current state from which to start simulation.
+ *  engine: The game engine with which to step through game logic.
+ *  runs: The number of times run a given determinization.
+ *  deters: The number of determinizations to run through.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The move picked by the final selection of the options and its mean reward
+ *  from the point of view of the player to move.
+ */
+func MCTSWithOptions(s State, engine TSEngine, runs int, deters int,
+                     opts MCTSOptions) (Move, float64) {
+    return MCTSReport(s, engine, runs, deters, opts).Best()
+}
 
-            conv[topMove.Action] = topMove
-            weights[topMove.Action] += topNode.GetPriority()
-            counts[topMove.Action] += 1
-        }
-    }
 
-    var maxMove Move
-    maxWeight := math.Inf(-1)
-    for hash, weight := range weights {
-        if weight > maxWeight {
-            maxMove = conv[hash]
-            maxWeight = weight
-        }
-    }
+/*
+ * Performs a Monte Carlo Tree search as in MCTSWithOptions, but gives a full
+ * report with the statistics of every root action rather than just the chosen
+ * move. The statistics of an action are summed over the trees of every
+ * determinization it was legal in.
+ *
+ * Args:
+ *  s: The current state from which to start simulation.
+ *  engine: The game engine with which to step through game logic.
+ *  runs: The number of times run a given determinization.
+ *  deters: The number of determinizations to run through.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The report of the search. The actions are in the order they were first
+ *  seen, so that ties are broken the same way for the same seed.
+ */
+func MCTSReport(s State, engine TSEngine, runs int, deters int,
+                opts MCTSOptions) Report {
+    return NewMCTSForest().Report(s, engine, runs, deters, opts)
+}
 
-    return maxMove, maxWeight / float64(counts[maxMove.Action])
+
+/*
+ * Performs a Monte Carlo Tree search as in MCTSReport, but over
+ * determinizations that are given along with how likely each one is, rather
+ * than ones drawn uniformly from the state. The runs times the number of
+ * determinizations playouts are split between them by weight, so that the
+ * report is a weighted average over the worlds. A determinization with no
+ * weight is not searched.
+ *
+ * Args:
+ *  deals: The determinizations to search and their weights.
+ *  engine: The game engine with which to step through game logic.
+ *  runs: The average number of times to run a determinization.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The report of the search.
+ */
+func MCTSWeightedReport(deals []Determinization, engine TSEngine, runs int,
+                        opts MCTSOptions) Report {
+    return NewMCTSForest().WeightedReport(deals, engine, runs, opts)
 }
 
 
@@ -175,7 +326,7 @@ func MCTS(s State, engine TSEngine, runs int, deters int) (Move, float64) {
  *  engine's computation.
  */
 func RunPlayoutDebug(node *Node, engine TSEngine) float64 {
-    return runPlayout(node, engine, true)
+    return runPlayout(node, engine, DefaultMCTSOptions(), true)
 }
 
 
@@ -191,7 +342,35 @@ func RunPlayoutDebug(node *Node, engine TSEngine) float64 {
  *  engine's computation.
  */
 func RunPlayout(node *Node, engine TSEngine) float64 {
-    return runPlayout(node, engine, false)
+    return runPlayout(node, engine, DefaultMCTSOptions(), false)
+}
+
+
+/*
+ * Build the MCTS tree of a single determinization of the given state, in the
+ * same way MCTS builds each of its trees. This is meant for inspecting a
+ * search, for example with WriteDOT or WriteJSON.
+ *
+ * Args:
+ *  s: The state to search from. It is copied and determinized.
+ *  engine: The engine for traversing the tree.
+ *  runs: The number of playouts to run.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The root of the tree.
+ */
+func SearchTree(s State, engine TSEngine, runs int, opts MCTSOptions) *Node {
+    copyState := s.Copy()
+    copyState.Determinize()
+
+    n := NewNode()
+    n.Value(Move{ nil, copyState })
+    for i := 0; i < runs; i++ {
+        runPlayout(n, engine, opts, false)
+    }
+
+    return n
 }
 
 
@@ -202,13 +381,56 @@ func RunPlayout(node *Node, engine TSEngine) float64 {
  * Args:
  *  node   - A node in the MCTS tree to start from.
  *  engine - The engine for traversing the MCTS tree.
+ *  opts   - The options for the search, such as the tree policy.
  *  log    - A flag to indicate whether the function should log.
  *
  * Returns:
  *  An integer that represents the final terminal state of the playout per the
  *  engine's computation.
  */
-func runPlayout(node *Node, engine TSEngine, log bool) float64 {
+func runPlayout(node *Node, engine TSEngine, opts MCTSOptions,
+                log bool) float64 {
+    var steps *[]amafStep
+    if opts.AMAF {
+        steps = new([]amafStep)
+    }
+
+    // A playout expands at most one node, so the tree is recycled before it
+    // starts if the children of the widest node seen so far would not fit.
+    // Otherwise the expansion would be refused instead.
+    if opts.Recycle && opts.MaxNodes > 0 &&
+       node.arena.used + node.arena.widest > opts.MaxNodes {
+        target := opts.MaxNodes * 3 / 4
+        if opts.MaxNodes - node.arena.widest < target {
+            target = opts.MaxNodes - node.arena.widest
+        }
+        recycle(node, target)
+    }
+
+    cut := newSearchCutoff(node.GetState(), engine, opts.Cutoff, opts.Heuristic)
+    return playout(node, engine, opts, log, steps, cut)
+}
+
+
+/*
+ * The recursive part of an MCTS playout. If AMAF statistics are tracked, every
+ * move made below the given node is added to steps, so that each node on the
+ * way back up can credit its children.
+ *
+ * Args:
+ *  node   - A node in the MCTS tree to start from.
+ *  engine - The engine for traversing the MCTS tree.
+ *  opts   - The options for the search, such as the tree policy.
+ *  log    - A flag to indicate whether the function should log.
+ *  steps  - The moves made so far below this node, or nil if AMAF statistics
+ *           are not tracked.
+ *  cut    - The cutoff of the search.
+ *
+ * Returns:
+ *  The evaluation of the final state of the playout.
+ */
+func playout(node *Node, engine TSEngine, opts MCTSOptions, log bool,
+             steps *[]amafStep, cut searchCutoff) float64 {
     if log {
         fmt.Println(node.GetState())
     }
@@ -220,6 +442,8 @@ func runPlayout(node *Node, engine TSEngine, log bool) float64 {
     // return and backpropagate the results.
     if engine.IsTerminal(node.GetState()) {
         eval = engine.Evaluation(node.GetState())
+    } else if cut.stop(node.GetState(), node.depth) {
+        eval = cut.heuristic(node.GetState())
     } else {
         var nextMoves []Move
         if node.depth <= 2 {
@@ -234,30 +458,60 @@ func runPlayout(node *Node, engine TSEngine, log bool) float64 {
 
         var next *Node
 
-        // If we don't have data on all the posssible next states, select one at
-        // random. Otherwise, choose the one with the highest UCB.
-        if len(nextMoves) > node.children.Len() {
-            takenMoves := make(map[interface{}]int)
-
-            for i := 0; i < node.children.Len(); i++ {
-                takenMoves[node.children[i].(*Node).GetMove().Action] = i
+        // If we don't have data on all the posssible next states, add one of
+        // the missing ones at random to the tree. The rest of the game is then
+        // played out by the rollout policy without adding any more nodes. If
+        // the tree is full and there is no room for the children, this node is
+        // played out from instead. Otherwise, let the tree policy choose.
+        full := node.parent != nil && node.children == nil &&
+                opts.MaxNodes > 0 &&
+                node.arena.used + len(nextMoves) > opts.MaxNodes
+        if full {
+            return rollout(node.GetState(), engine, opts.Rollout, log, steps,
+                           cut, node.depth)
+        } else if len(nextMoves) > len(node.children) {
+            // There are only a few moves from any state, so a scan of the
+            // children is cheaper than building a set of the taken moves.
+            untried := make([]Move, 0, len(nextMoves))
+            for _, move := range nextMoves {
+                taken := false
+                for i := range node.children {
+                    if node.children[i].value.Action == move.Action {
+                        taken = true
+                        break
+                    }
+                }
+
+                if !taken {
+                    untried = append(untried, move)
+                }
             }
+            nextMove := untried[rng.Intn(len(untried))]
 
-            nextMove := nextMoves[r.Intn(len(nextMoves))]
+            next = node.addChild(nextMove, len(nextMoves))
+            next.prior = movePrior(node.GetState(), nextMoves, nextMove, opts)
 
-            if _, ok := takenMoves[nextMove.Action]; ok {
-                next = node.children[takenMoves[nextMove.Action]].(*Node)
-            } else {
-                next = NewNode()
-                next.Value(nextMove)
-                next.parent = node
-                next.depth = node.depth + 1
-                heap.Push(&node.children, next)
-            }
+            next.simulations++
+            eval = rollout(next.GetMove().State, engine, opts.Rollout, log,
+                           steps, cut, next.depth)
         } else {
-            next = node.children.Poll().(*Node)
+            stats := make([]ChildStats, len(node.children))
+            for i := range stats {
+                child := &node.children[i]
+                stats[i] = ChildStats {
+                    Visits: child.simulations,
+                    ParentVisits: node.simulations,
+                    Mean: child.Mean(),
+                    Variance: child.Variance(),
+                    Prior: child.prior,
+                    AmafVisits: child.amafSimulations,
+                    AmafMean: child.AmafMean(),
+                }
+            }
+
+            next = &node.children[opts.Policy.Select(stats)]
+            eval = playout(next, engine, opts, log, steps, cut)
         }
-        eval = runPlayout(next, engine, log)
 
         adjEval := eval
         if adjEval < 0 {
@@ -265,15 +519,56 @@ func runPlayout(node *Node, engine TSEngine, log bool) float64 {
         }
 
         fav := engine.Favorable(node.GetState())
+        signed := adjEval
         if (fav && eval > 0) || (!fav && eval < 0) {
             next.eval += adjEval
         } else {
             next.eval -= adjEval
+            signed = -adjEval
+        }
+        next.sumSquares += eval * eval
+        // The first visit of a new node has already been counted.
+        if next.simulations == 1 || signed < next.min {
+            next.min = signed
+        }
+        if next.simulations == 1 || signed > next.max {
+            next.max = signed
         }
 
-        next.Priority(UpperConfBound(next))
-        node.children.Update(next, next.GetValue(), next.GetPriority())
+        if steps != nil {
+            *steps = append(*steps, amafStep{ next.GetMove().Action, fav })
+            updateAmaf(node, *steps, fav, eval)
+        }
     }
 
     return eval
 }
+
+
+/*
+ * The prior probability of a move among all the moves from a state, as given
+ * by the priors of the options.
+ *
+ * Args:
+ *  state: The state the moves are from.
+ *  moves: All the moves from the state.
+ *  move: The move to find the prior of.
+ *  opts: The options with the priors function.
+ *
+ * Returns:
+ *  The prior of the move. This is uniform if the options have no priors.
+ */
+func movePrior(state TSState, moves []Move, move Move, opts MCTSOptions) float64 {
+    if opts.Priors == nil {
+        return 1.0 / float64(len(moves))
+    }
+
+    priors := opts.Priors(state, moves)
+    for i, m := range moves {
+        if m.Action == move.Action {
+            return priors[i]
+        }
+    }
+
+    return 0
+}
@@ -16,7 +16,31 @@ import "math"
  *  and the state it will send you to.
  */
 func Minimax(state TSState, engine TSEngine) (float64, Move) {
-    return minimaxHelper(state, engine, math.Inf(-1), math.Inf(1))
+    return minimaxHelper(state, engine, math.Inf(-1), math.Inf(1),
+                         searchCutoff{ }, 0)
+}
+
+
+/*
+ * Uses minimax in the same way as Minimax, but stops where the cutoff says to
+ * and scores those states with a heuristic instead of searching to the end of
+ * the game.
+ *
+ * Args:
+ *  state: The state to start the search from.
+ *  engine: The game logic engine for the tree search.
+ *  cutoff: Where to stop the search.
+ *  heuristic: The heuristic to score the states the search stops at. If it is
+ *             nil, the engine's own is used if it is a HeuristicEngine.
+ *
+ * Returns:
+ *  The evaluation for the best state and the Move struct associated with it,
+ *  as for Minimax.
+ */
+func MinimaxWithCutoff(state TSState, engine TSEngine, cutoff Cutoff,
+                       heuristic func(state TSState) float64) (float64, Move) {
+    cut := newSearchCutoff(state, engine, cutoff, heuristic)
+    return minimaxHelper(state, engine, math.Inf(-1), math.Inf(1), cut, 0)
 }
 
 
@@ -29,6 +53,8 @@ func Minimax(state TSState, engine TSEngine) (float64, Move) {
  *  engine: The logic engine for the tree search.
  *  alpha: The current alpha value. This should be set to -inf when first called.
  *  beta: The current beta value. This should be set to +inf when first called.
+ *  cut: The cutoff of the search.
+ *  depth: The number of moves from the root of the search to state.
  *
  * Returns:
  *  Gives both the evaluation for the best state and the Move struct associated
@@ -36,11 +62,15 @@ func Minimax(state TSState, engine TSEngine) (float64, Move) {
  *  and the state it will send you to.
  */
 func minimaxHelper(state TSState, engine TSEngine, alpha float64,
-                   beta float64) (float64, Move) {
+                   beta float64, cut searchCutoff, depth int) (float64, Move) {
     if engine.IsTerminal(state) {
         return engine.Evaluation(state), Move { nil, state }
     }
 
+    if cut.stop(state, depth) {
+        return cut.heuristic(state), Move { nil, state }
+    }
+
     fav := engine.Favorable(state)
 
     var extremeMove Move
@@ -53,7 +83,8 @@ func minimaxHelper(state TSState, engine TSEngine, alpha float64,
 
     for _, nextMove := range engine.Successors(state) {
         nextState := nextMove.State
-        nextEval, _ := minimaxHelper(nextState, engine, alpha, beta)
+        nextEval, _ := minimaxHelper(nextState, engine, alpha, beta, cut,
+                                     depth + 1)
 
         if fav {
             if nextEval > extremeValue {
@@ -0,0 +1,67 @@
+package ai
+
+import "math"
+
+
+/*
+ * Perfect Information Monte Carlo search. The given state is determinized a
+ * number of times and each determinization is solved exactly as if it were a
+ * game of perfect information. Every action from the root is scored in every
+ * determinization by running minimax on its successor, and the action with the
+ * best average is chosen. If the state has no more determinizations than
+ * deters, every one of them is solved instead, and the average is weighted.
+ *
+ * Note that each successor is solved with a full alpha-beta window. Pruning
+ * across siblings would only give bounds for the actions that are not the best
+ * in a determinization, and the average needs exact values for all of them.
+ *
+ * Args:
+ *  s: The current state to search from. This should not be terminal.
+ *  engine: The game engine with which to step through game logic.
+ *  deters: The number of determinizations to solve.
+ *
+ * Returns:
+ *  The move with the highest average evaluation and that average. The average
+ *  is from the point of view of the player to move, as in MCTS. The state of
+ *  the returned move is the successor from the last determinization the action
+ *  was seen in.
+ */
+func PIMC(s State, engine TSEngine, deters int) (Move, float64) {
+    sums := make(map[interface{}]float64)
+    weights := make(map[interface{}]float64)
+    conv := make(map[interface{}]Move)
+    // The order actions were first seen in, so that ties are broken the same
+    // way for the same seed.
+    order := make([]interface{}, 0)
+
+    for _, world := range Worlds(s, deters) {
+        d := world.State
+
+        fav := engine.Favorable(d)
+        for _, move := range engine.Successors(d) {
+            eval, _ := Minimax(move.State, engine)
+            if !fav {
+                eval = -eval
+            }
+
+            if _, ok := conv[move.Action]; !ok {
+                order = append(order, move.Action)
+            }
+            conv[move.Action] = move
+            sums[move.Action] += world.Weight * eval
+            weights[move.Action] += world.Weight
+        }
+    }
+
+    var maxMove Move
+    maxAvg := math.Inf(-1)
+    for _, action := range order {
+        avg := sums[action] / weights[action]
+        if avg > maxAvg {
+            maxMove = conv[action]
+            maxAvg = avg
+        }
+    }
+
+    return maxMove, maxAvg
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+    "math"
+    "rng"
+)
+
+
+/*
+ * The statistics of a child node that a selection policy can base its choice
+ * on. ParentVisits is normally the visit count of the parent, but for searches
+ * where a child is not always legal, such as ISMCTS, it is how many times the
+ * child was available for selection. The AMAF statistics are only filled in
+ * when they are tracked.
+ */
+type ChildStats struct {
+    Visits int
+    ParentVisits int
+    Mean float64
+    Variance float64
+    Prior float64
+    AmafVisits int
+    AmafMean float64
+}
+
+
+/*
+ * A tree policy for MCTS. When every child of a node has been expanded, the
+ * policy is given the statistics of each child and decides which one to
+ * descend to. Children are scored at selection time so no value goes stale as
+ * the parent's visit count grows.
+ */
+type SelectionPolicy interface {
+    /*
+     * Choose the child to descend to.
+     *
+     * Args:
+     *  children: The statistics of each child. There is at least one child.
+     *
+     * Returns:
+     *  The index of the chosen child in children.
+     */
+    Select(children []ChildStats) int
+}
+
+
+/*
+ * UCB1 with a configurable exploration constant, C. A child is scored as
+ * mean + C * sqrt(ln(parent visits) / visits). Unvisited children are always
+ * chosen first.
+ */
+type UCB1 struct {
+    C float64
+}
+
+
+func (p UCB1) Select(children []ChildStats) int {
+    return argmax(children, func(c ChildStats) float64 {
+        if c.Visits == 0 {
+            return math.Inf(1)
+        }
+
+        return c.Mean + p.C * math.Sqrt(math.Log(float64(c.ParentVisits)) /
+                                        float64(c.Visits))
+    })
+}
+
+
+/*
+ * UCB1-Tuned, which replaces the fixed exploration term of UCB1 with one based
+ * on the observed variance of each child. Bound is the largest variance a
+ * reward can have, which is 1/4 for rewards in [0, 1], and (max - min)^2 / 4 in
+ * general.
+ */
+type UCB1Tuned struct {
+    Bound float64
+}
+
+
+func (p UCB1Tuned) Select(children []ChildStats) int {
+    return argmax(children, func(c ChildStats) float64 {
+        if c.Visits == 0 {
+            return math.Inf(1)
+        }
+
+        logN := math.Log(float64(c.ParentVisits))
+        n := float64(c.Visits)
+        v := c.Variance + math.Sqrt(2.0 * logN / n)
+
+        return c.Mean + math.Sqrt(logN / n * math.Min(p.Bound, v))
+    })
+}
+
+
+/*
+ * PUCT, the predictor variant of UCB used by AlphaZero. A child is scored as
+ * mean + C * prior * sqrt(parent visits) / (1 + visits), so that exploration is
+ * spread according to the priors. Unvisited children have a mean of 0.
+ */
+type PUCT struct {
+    C float64
+}
+
+
+func (p PUCT) Select(children []ChildStats) int {
+    return argmax(children, func(c ChildStats) float64 {
+        return c.Mean + p.C * c.Prior * math.Sqrt(float64(c.ParentVisits)) /
+                        float64(1 + c.Visits)
+    })
+}
+
+
+/*
+ * Epsilon-greedy selection. With probability Epsilon a child is chosen
+ * uniformly at random, and otherwise the child with the best mean is chosen.
+ * Unvisited children are always chosen first.
+ */
+type EpsilonGreedy struct {
+    Epsilon float64
+}
+
+
+func (p EpsilonGreedy) Select(children []ChildStats) int {
+    if rng.Float64() < p.Epsilon {
+        return rng.Intn(len(children))
+    }
+
+    return argmax(children, func(c ChildStats) float64 {
+        if c.Visits == 0 {
+            return math.Inf(1)
+        }
+
+        return c.Mean
+    })
+}
+
+
+/*
+ * Find the child with the highest score. Ties go to the first such child.
+ *
+ * Args:
+ *  children: The statistics of each child.
+ *  score: The scoring function for a child.
+ *
+ * Returns:
+ *  The index of the child with the highest score.
+ */
+func argmax(children []ChildStats, score func(ChildStats) float64) int {
+    best := 0
+    bestScore := math.Inf(-1)
+    for i, c := range children {
+        s := score(c)
+        if i == 0 || s > bestScore {
+            best = i
+            bestScore = s
+        }
+    }
+
+    return best
+}
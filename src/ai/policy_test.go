@@ -0,0 +1,162 @@
+package ai
+
+import (
+    "math"
+    "rng"
+    "testing"
+)
+
+
+/*
+ * A test that defines the child statistics given to a policy and the child
+ * that should be selected.
+ */
+type policyTest struct {
+    policy SelectionPolicy
+    children []ChildStats
+    expected int
+}
+
+
+var policyTests = []policyTest {
+    /*
+     * Unvisited children are always tried first by UCB1.
+     */
+    policyTest {
+        UCB1{ math.Sqrt2 },
+        []ChildStats {
+            ChildStats { Visits: 10, ParentVisits: 11, Mean: 4 },
+            ChildStats { Visits: 0, ParentVisits: 11, Mean: 0 },
+        },
+        1,
+    },
+
+    /*
+     * With no exploration UCB1 is greedy.
+     */
+    policyTest {
+        UCB1{ 0 },
+        []ChildStats {
+            ChildStats { Visits: 1, ParentVisits: 100, Mean: 1 },
+            ChildStats { Visits: 99, ParentVisits: 100, Mean: 2 },
+        },
+        1,
+    },
+
+    /*
+     * With a large constant UCB1 prefers the less visited child.
+     */
+    policyTest {
+        UCB1{ 10 },
+        []ChildStats {
+            ChildStats { Visits: 1, ParentVisits: 100, Mean: 1 },
+            ChildStats { Visits: 99, ParentVisits: 100, Mean: 2 },
+        },
+        0,
+    },
+
+    /*
+     * UCB1-Tuned explores a child with high variance more than one with the
+     * same mean and visits but no variance.
+     */
+    policyTest {
+        UCB1Tuned{ 16 },
+        []ChildStats {
+            ChildStats { Visits: 50, ParentVisits: 100, Mean: 1, Variance: 0 },
+            ChildStats { Visits: 50, ParentVisits: 100, Mean: 1, Variance: 4 },
+        },
+        1,
+    },
+
+    /*
+     * PUCT explores the child with the higher prior when the means are equal.
+     */
+    policyTest {
+        PUCT{ 1 },
+        []ChildStats {
+            ChildStats { Visits: 5, ParentVisits: 10, Mean: 0, Prior: 0.2 },
+            ChildStats { Visits: 5, ParentVisits: 10, Mean: 0, Prior: 0.8 },
+        },
+        1,
+    },
+
+    /*
+     * Epsilon-greedy with no exploration picks the best mean.
+     */
+    policyTest {
+        EpsilonGreedy{ 0 },
+        []ChildStats {
+            ChildStats { Visits: 5, ParentVisits: 10, Mean: 3 },
+            ChildStats { Visits: 5, ParentVisits: 10, Mean: -1 },
+        },
+        0,
+    },
+
+    /*
+     * RAVE prefers the child whose AMAF mean is high while it has few visits.
+     */
+    policyTest {
+        RAVE{ UCB1{ 0 }, 100 },
+        []ChildStats {
+            ChildStats { Visits: 2, ParentVisits: 4, Mean: 1, AmafVisits: 10,
+                         AmafMean: 1 },
+            ChildStats { Visits: 2, ParentVisits: 4, Mean: 0, AmafVisits: 10,
+                         AmafMean: 3 },
+        },
+        1,
+    },
+
+    /*
+     * Once a child is well visited its own mean dominates the AMAF mean.
+     */
+    policyTest {
+        RAVE{ UCB1{ 0 }, 1 },
+        []ChildStats {
+            ChildStats { Visits: 1000, ParentVisits: 2000, Mean: 1,
+                         AmafVisits: 1500, AmafMean: 1 },
+            ChildStats { Visits: 1000, ParentVisits: 2000, Mean: 0,
+                         AmafVisits: 1500, AmafMean: 3 },
+        },
+        0,
+    },
+}
+
+
+/*
+ * The main driver for the policy tests outlined above.
+ */
+func TestSelect(t *testing.T) {
+    for i, fixture := range policyTests {
+        chosen := fixture.policy.Select(fixture.children)
+        if chosen != fixture.expected {
+            t.Errorf("Fixture %d gave %d instead of %d.\n", i + 1, chosen,
+                     fixture.expected)
+        }
+    }
+}
+
+
+/*
+ * Test that epsilon-greedy selection explores with roughly the right
+ * probability.
+ */
+func TestEpsilonGreedyExplores(t *testing.T) {
+    rng.Seed(1)
+    policy := EpsilonGreedy{ 0.5 }
+    children := []ChildStats {
+        ChildStats { Visits: 5, ParentVisits: 10, Mean: 3 },
+        ChildStats { Visits: 5, ParentVisits: 10, Mean: -1 },
+    }
+
+    worse := 0
+    for i := 0; i < 10000; i++ {
+        if policy.Select(children) == 1 {
+            worse++
+        }
+    }
+
+    // The worse child is chosen half of the times it explores.
+    if worse < 2250 || worse > 2750 {
+        t.Errorf("The worse child was chosen %d times out of 10000.\n", worse)
+    }
+}
@@ -0,0 +1,79 @@
+package ai
+
+import "math"
+
+
+/*
+ * A move made during a playout, along with whether the side that made it was
+ * the favorable one. This is all that is needed to credit All-Moves-As-First
+ * statistics.
+ */
+type amafStep struct {
+    action interface{}
+    fav bool
+}
+
+
+/*
+ * Rapid Action Value Estimation. The mean of each child is blended with its
+ * All-Moves-As-First mean before the base policy chooses. AMAF statistics
+ * count every playout through the parent in which the child's action was
+ * played at any later point by the same side, so they build up much faster
+ * than the normal statistics but are biased. The weight of the AMAF mean is
+ * sqrt(K / (3 * visits + K)), so it fades as the child gets visited, and K is
+ * the number of visits at which both means count equally.
+ *
+ * The AMAF statistics are only tracked when the AMAF field of MCTSOptions is
+ * set. Otherwise this is the same as the base policy.
+ */
+type RAVE struct {
+    Policy SelectionPolicy
+    K float64
+}
+
+
+func (p RAVE) Select(children []ChildStats) int {
+    blended := make([]ChildStats, len(children))
+    for i, c := range children {
+        blended[i] = c
+        if c.AmafVisits > 0 {
+            beta := math.Sqrt(p.K / (3.0 * float64(c.Visits) + p.K))
+            blended[i].Mean = (1 - beta) * c.Mean + beta * c.AmafMean
+        }
+    }
+
+    return p.Policy.Select(blended)
+}
+
+
+/*
+ * Credit the AMAF statistics of a node's children from a playout through the
+ * node. A child is credited if its action was made by the side that moves at
+ * the node at any point at or after the node.
+ *
+ * Args:
+ *  node: The node whose children are credited.
+ *  steps: Every move made from the node to the end of the playout.
+ *  fav: If the side that moves at the node is the favorable one.
+ *  eval: The evaluation of the playout.
+ */
+func updateAmaf(node *Node, steps []amafStep, fav bool, eval float64) {
+    played := make(map[interface{}]bool)
+    for _, step := range steps {
+        if step.fav == fav {
+            played[step.action] = true
+        }
+    }
+
+    if !fav {
+        eval = -eval
+    }
+
+    for i := range node.children {
+        child := &node.children[i]
+        if played[child.GetMove().Action] {
+            child.amafSimulations++
+            child.amafEval += eval
+        }
+    }
+}
@@ -0,0 +1,38 @@
+package ai
+
+import "testing"
+
+
+/*
+ * Test that AMAF statistics are only credited to the children whose action was
+ * made later in the playout by the same side, and from that side's point of
+ * view.
+ */
+func TestUpdateAmaf(t *testing.T) {
+    root := NewNode()
+    children := make([]*Node, 3)
+    for i := range children {
+        children[i] = root.addChild(Move{ i, nil }, len(children))
+    }
+
+    steps := []amafStep {
+        amafStep{ 0, false },
+        amafStep{ 1, true },
+        amafStep{ 2, false },
+        amafStep{ 2, true },
+    }
+    updateAmaf(root, steps, false, 3)
+
+    expected := []int{ 1, 0, 1 }
+    for i, child := range children {
+        if child.GetAmafSimulations() != expected[i] {
+            t.Errorf("Child %d has %d AMAF visits instead of %d.\n", i,
+                     child.GetAmafSimulations(), expected[i])
+        }
+    }
+
+    if children[0].AmafMean() != -3 {
+        t.Errorf("Child 0 has an AMAF mean of %f instead of -3.\n",
+                 children[0].AmafMean())
+    }
+}
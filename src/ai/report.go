@@ -0,0 +1,165 @@
+package ai
+
+import (
+    "math"
+    "time"
+)
+
+
+/*
+ * The statistics of a root action once a search is done. Mean is the true
+ * average reward of the action from the point of view of the player to move at
+ * the root, with no exploration bonus, and StdErr is the standard error of that
+ * mean. Min and Max are the worst and best rewards of any playout through the
+ * action from the same point of view. Determinizations is how many of the
+ * determinizations the action was legal in. Move is the action along with a
+ * successor state from one of those determinizations.
+ */
+type ActionStats struct {
+    Move Move
+    Visits int
+    Mean float64
+    Variance float64
+    StdErr float64
+    Min float64
+    Max float64
+    Determinizations int
+}
+
+
+/*
+ * The full result of a search, for analysis and for explaining a decision.
+ * Actions has the statistics of every root action, and Chosen is the index of
+ * the action that the final selection picked, or -1 if there are no actions.
+ * Playouts is the total number of playouts that were run and Duration is how
+ * long the search took. Reused is the number of root visits that were kept
+ * from the searches of earlier decisions, which is 0 unless a tree is reused.
+ * Separated is whether the best action was separated from the rest when the
+ * search ended, which is only checked if the search could stop early.
+ */
+type Report struct {
+    Actions []ActionStats
+    Chosen int
+    Playouts int
+    Reused int
+    Separated bool
+    Duration time.Duration
+}
+
+
+/*
+ * The move that the search picked along with its mean reward, which is what
+ * the search functions that only give a move return.
+ *
+ * Returns:
+ *  The chosen move and its mean reward, or an empty move and 0 if there were
+ *  no actions.
+ */
+func (r Report) Best() (Move, float64) {
+    if r.Chosen < 0 {
+        return Move{ }, 0
+    }
+
+    return r.Actions[r.Chosen].Move, r.Actions[r.Chosen].Mean
+}
+
+
+/*
+ * Fill in the final choice and the run time of a report.
+ *
+ * Args:
+ *  actions: The statistics of every root action.
+ *  playouts: The number of playouts that were run.
+ *  start: When the search started.
+ *  final: The strategy to pick the final action with.
+ *
+ * Returns:
+ *  The complete report.
+ */
+func newReport(actions []ActionStats, playouts int, start time.Time,
+               final FinalSelection) Report {
+    chosen := -1
+    if len(actions) > 0 {
+        chosen = SelectFinal(actions, final)
+    }
+
+    return Report {
+        Actions: actions,
+        Chosen: chosen,
+        Playouts: playouts,
+        Duration: time.Since(start),
+    }
+}
+
+
+/*
+ * The running totals of a root action, which are summed over every tree that
+ * the action was in.
+ */
+type actionTotals struct {
+    move Move
+    visits int
+    eval float64
+    sumSquares float64
+    min float64
+    max float64
+    determinizations int
+}
+
+
+/*
+ * Add the statistics of a node to the totals.
+ *
+ * Args:
+ *  move: The move that leads to the node.
+ *  visits: The number of times the node was visited.
+ *  eval: The sum of the node's rewards.
+ *  sumSquares: The sum of the squares of the node's rewards.
+ *  min: The node's worst reward.
+ *  max: The node's best reward.
+ */
+func (t *actionTotals) add(move Move, visits int, eval, sumSquares, min,
+                           max float64) {
+    t.move = move
+    if visits == 0 {
+        return
+    }
+
+    if t.visits == 0 || min < t.min {
+        t.min = min
+    }
+    if t.visits == 0 || max > t.max {
+        t.max = max
+    }
+
+    t.visits += visits
+    t.eval += eval
+    t.sumSquares += sumSquares
+}
+
+
+/*
+ * Turn the totals into the final statistics of the action.
+ *
+ * Returns:
+ *  The statistics of the action.
+ */
+func (t *actionTotals) stats() ActionStats {
+    stats := ActionStats {
+        Move: t.move,
+        Visits: t.visits,
+        Min: t.min,
+        Max: t.max,
+        Determinizations: t.determinizations,
+    }
+    if t.visits == 0 {
+        return stats
+    }
+
+    n := float64(t.visits)
+    stats.Mean = t.eval / n
+    stats.Variance = math.Max(0, t.sumSquares / n - stats.Mean * stats.Mean)
+    stats.StdErr = math.Sqrt(stats.Variance / n)
+
+    return stats
+}
@@ -0,0 +1,591 @@
+package ai
+
+import (
+    "math"
+    "time"
+)
+
+
+/*
+ * The trees of a determinized MCTS that are kept between the decisions of one
+ * game. After a decision, the actions that were then observed move the root of
+ * every tree down to the node they lead to, so the statistics that were
+ * gathered below that node carry on to the next decision. A tree whose
+ * determinization does not allow one of the observed actions could not be the
+ * real world, and is thrown away.
+ *
+ * If the trees are every determinization of the state, or were given with
+ * weights to WeightedReport, weights has the weight of each one. Otherwise it
+ * is nil, and every tree has the same weight.
+ */
+type MCTSForest struct {
+    trees []*Node
+    weights []float64
+}
+
+
+/*
+ * Create a forest with no trees. Its first search is the same as MCTSReport.
+ *
+ * Returns:
+ *  An empty forest.
+ */
+func NewMCTSForest() *MCTSForest {
+    return &MCTSForest{ }
+}
+
+
+/*
+ * The roots of the trees that are kept for the next search.
+ *
+ * Returns:
+ *  The root of every tree in the forest.
+ */
+func (f *MCTSForest) Trees() []*Node {
+    return f.trees
+}
+
+
+/*
+ * Throw away every tree, such as when a new game starts.
+ */
+func (f *MCTSForest) Reset() {
+    f.trees = nil
+    f.weights = nil
+}
+
+
+/*
+ * Move the root of every tree along the given actions, in the order they were
+ * played. Trees in which an action is not legal are thrown away. If the engine
+ * is a MergingEngine, each action is followed by the action that stands for it.
+ *
+ * Args:
+ *  engine: The engine for the game logic.
+ *  actions: The actions that were played since the last search.
+ */
+func (f *MCTSForest) Advance(engine TSEngine, actions []interface{}) {
+    kept := make([]*Node, 0, len(f.trees))
+    var weights []float64
+    for i, root := range f.trees {
+        for _, action := range actions {
+            if root = advanceNode(root, engine, action); root == nil {
+                break
+            }
+        }
+
+        if root != nil && !engine.IsTerminal(root.GetState()) {
+            kept = append(kept, root)
+            if f.weights != nil {
+                weights = append(weights, f.weights[i])
+            }
+        }
+    }
+
+    f.trees = kept
+    f.weights = weights
+}
+
+
+/*
+ * Search from the given state with the trees that are kept, and give a report
+ * in the same way as MCTSReport. The trees that are kept only run the playouts
+ * they need to have runs visits at their root, and new determinizations are
+ * added until there are deters trees. If more trees are kept than deters, the
+ * extra trees are thrown away. Every tree is kept for the next search.
+ *
+ * If the state has no more determinizations than deters, and the trees are not
+ * already every one of them, the trees are replaced with one for each. The
+ * runs times deters playouts are then split between them by weight, so that
+ * the report is a weighted average over every world.
+ *
+ * If the options can stop early, the trees take turns running Interval
+ * playouts each, and the search ends once the best root action is separated
+ * from the rest, or every tree has reached its limit.
+ *
+ * Args:
+ *  s: The current state from which to start simulation.
+ *  engine: The game engine with which to step through game logic.
+ *  runs: The number of visits each determinization should have at its root.
+ *  deters: The number of determinizations to search.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The report of the search. Playouts is the number of playouts that were run
+ *  by this search, and Reused is the number of root visits that were kept from
+ *  earlier searches.
+ */
+func (f *MCTSForest) Report(s State, engine TSEngine, runs, deters int,
+                            opts MCTSOptions) Report {
+    start := time.Now()
+    reused := 0
+
+    if f.weights == nil {
+        if deals := Enumerate(s, deters); deals != nil {
+            f.trees = make([]*Node, len(deals))
+            f.weights = make([]float64, len(deals))
+            for i, deal := range deals {
+                f.trees[i] = NewNode()
+                f.trees[i].Value(Move{ nil, deal.State })
+                f.weights[i] = deal.Weight
+            }
+        }
+    }
+
+    if f.weights == nil && len(f.trees) > deters {
+        f.trees = f.trees[:deters]
+    }
+
+    for _, n := range f.trees {
+        reused += n.simulations
+    }
+
+    for f.weights == nil && len(f.trees) < deters {
+        copyState := s.Copy()
+        copyState.Determinize()
+
+        n := NewNode()
+        n.Value(Move{ nil, copyState })
+        f.trees = append(f.trees, n)
+    }
+
+    return f.search(engine, runs, deters, opts, start, reused)
+}
+
+
+/*
+ * Search the given determinizations, weighted by how likely each is, and give a
+ * report in the same way as MCTSReport. Every tree in the forest is replaced
+ * with one for each determinization that has a positive weight, and the runs
+ * times the number of determinizations playouts are split between them by
+ * weight. If none of them has a positive weight, they are all searched with
+ * the same weight. The trees are kept for the next search, and a later Report
+ * uses them as they are rather than drawing new determinizations.
+ *
+ * Args:
+ *  deals: The determinizations to search and their weights.
+ *  engine: The game engine with which to step through game logic.
+ *  runs: The average number of visits each determinization should have at its
+ *        root.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The report of the search.
+ */
+func (f *MCTSForest) WeightedReport(deals []Determinization, engine TSEngine,
+                                    runs int, opts MCTSOptions) Report {
+    start := time.Now()
+
+    f.trees = make([]*Node, 0, len(deals))
+    f.weights = make([]float64, 0, len(deals))
+    for _, deal := range deals {
+        // A world that can not be the real one would only take playouts.
+        if deal.Weight > 0 {
+            n := NewNode()
+            n.Value(Move{ nil, deal.State })
+            f.trees = append(f.trees, n)
+            f.weights = append(f.weights, deal.Weight)
+        }
+    }
+
+    if len(f.trees) == 0 {
+        for _, deal := range deals {
+            n := NewNode()
+            n.Value(Move{ nil, deal.State })
+            f.trees = append(f.trees, n)
+            f.weights = append(f.weights, 1)
+        }
+    }
+
+    return f.search(engine, runs, len(deals), opts, start, 0)
+}
+
+
+/*
+ * Run the playouts of a search over the trees of the forest.
+ *
+ * Args:
+ *  engine: The game engine with which to step through game logic.
+ *  runs: The number of visits each determinization should have at its root.
+ *  deters: The number of determinizations the budget is for.
+ *  opts: The options for the search.
+ *  start: When the search started.
+ *  reused: The root visits that were kept from earlier searches.
+ *
+ * Returns:
+ *  The report of the search.
+ */
+func (f *MCTSForest) search(engine TSEngine, runs, deters int,
+                            opts MCTSOptions, start time.Time,
+                            reused int) Report {
+    totals := make(map[interface{}]*actionTotals)
+    order := make([]interface{}, 0)
+    playouts := 0
+
+    limits := f.limits(runs, deters, opts.Stop)
+    interval := opts.Stop.interval(runs)
+    separated := false
+    for round, full := 0, false; !full && !separated; round++ {
+        full = true
+        for i, n := range f.trees {
+            limit := limits[i]
+            if round == 0 {
+                for _, move := range engine.Successors(n.GetState()) {
+                    if _, ok := totals[move.Action]; !ok {
+                        totals[move.Action] = &actionTotals{ move: move }
+                        order = append(order, move.Action)
+                    }
+                    totals[move.Action].determinizations++
+                }
+            }
+
+            for j := 0; j < interval && n.simulations < limit; j++ {
+                runPlayout(n, engine, opts, false)
+                playouts++
+            }
+
+            if n.simulations < limit {
+                full = false
+            }
+        }
+
+        // An action that was never tried could be the best.
+        if opts.Stop.enabled() {
+            actions := f.stats(totals, order)
+            separated = len(actions) == len(order) &&
+                        Separated(actions, opts.Stop.Z, opts.Stop.MinVisits)
+        }
+    }
+
+    report := newReport(f.stats(totals, order), playouts, start, opts.Final)
+    report.Reused = reused
+    report.Separated = separated
+
+    return report
+}
+
+
+/*
+ * The most visits the root of each tree may have in a search.
+ *
+ * Args:
+ *  runs: The number of visits each determinization should have at its root.
+ *  deters: The number of determinizations to search.
+ *  stop: When the search may stop early or run past its budget.
+ *
+ * Returns:
+ *  The limit of each tree. Sampled trees each get runs, and the runs times
+ *  deters playouts are split by weight between weighted trees.
+ */
+func (f *MCTSForest) limits(runs, deters int, stop EarlyStop) []int {
+    total := 0.0
+    for _, weight := range f.weights {
+        total += weight
+    }
+
+    limits := make([]int, len(f.trees))
+    for i := range limits {
+        budget := runs
+        if f.weights != nil {
+            share := f.weights[i] / total
+            budget = int(math.Max(1, math.Round(share * float64(runs * deters))))
+        }
+
+        limits[i] = stop.limit(budget)
+    }
+
+    return limits
+}
+
+
+/*
+ * Sum the statistics of the root actions over every tree.
+ *
+ * Args:
+ *  totals: The totals of every root action, with the determinizations they
+ *          were legal in. The visit statistics are replaced.
+ *  order: The root actions in the order they were first seen.
+ *
+ * Returns:
+ *  The statistics of every root action that was tried, in order.
+ */
+func (f *MCTSForest) stats(totals map[interface{}]*actionTotals,
+                           order []interface{}) []ActionStats {
+    for _, action := range order {
+        *totals[action] = actionTotals {
+            move: totals[action].move,
+            determinizations: totals[action].determinizations,
+        }
+    }
+
+    for _, n := range f.trees {
+        for j := range n.children {
+            child := &n.children[j]
+            totals[child.GetMove().Action].add(child.GetMove(),
+                                               child.simulations, child.eval,
+                                               child.sumSquares, child.min,
+                                               child.max)
+        }
+    }
+
+    actions := make([]ActionStats, 0, len(order))
+    for _, action := range order {
+        // Actions that were legal but never tried have nothing to report.
+        if totals[action].visits > 0 {
+            actions = append(actions, totals[action].stats())
+        }
+    }
+
+    return actions
+}
+
+
+/*
+ * Make the node that the given action leads to from a root into the root of its
+ * own tree. The rest of the old tree is given back to the arena. If the action
+ * is legal but was never expanded, the new root starts with no statistics.
+ *
+ * Args:
+ *  root: The root of a tree.
+ *  engine: The engine for the game logic.
+ *  action: The action that was played from the root's state.
+ *
+ * Returns:
+ *  The new root, or nil if the action is not legal in the root's state.
+ */
+func advanceNode(root *Node, engine TSEngine, action interface{}) *Node {
+    if merging, ok := engine.(MergingEngine); ok &&
+       !engine.IsTerminal(root.GetState()) {
+        action = merging.Representative(root.GetState(), action)
+    }
+
+    var next *Node
+    for i := range root.children {
+        if root.children[i].value.Action == action {
+            next = &root.children[i]
+        }
+    }
+
+    if next == nil {
+        if engine.IsTerminal(root.GetState()) {
+            return nil
+        }
+
+        for _, move := range engine.Successors(root.GetState()) {
+            if move.Action == action {
+                prune(root)
+                n := &Node{ arena: root.arena }
+                n.Value(move)
+                return n
+            }
+        }
+
+        return nil
+    }
+
+    // The new root is moved out of the run of its siblings so that the whole
+    // run can be released.
+    n := &Node{ }
+    *n = *next
+    n.parent = nil
+    for i := range n.children {
+        n.children[i].parent = n
+    }
+
+    for i := range root.children {
+        if &root.children[i] != next && root.children[i].children != nil {
+            prune(&root.children[i])
+        }
+    }
+    root.arena.release(root.children)
+    root.children = nil
+
+    setDepth(n, 0)
+    return n
+}
+
+
+/*
+ * Set the depth of a node and of every node below it.
+ *
+ * Args:
+ *  node: The node to start from.
+ *  depth: The depth of the node.
+ */
+func setDepth(node *Node, depth int) {
+    node.depth = depth
+    for i := range node.children {
+        setDepth(&node.children[i], depth + 1)
+    }
+}
+
+
+/*
+ * The tree of an Information Set MCTS that is kept between the decisions of one
+ * game. After a decision, the root is moved along the actions that were
+ * observed, in the same way as an MCTSForest. Since the tree is not tied to any
+ * one determinization, it is only started over if an action was never tried.
+ */
+type ISMCTSTree struct {
+    root *ISNode
+}
+
+
+/*
+ * Create an ISMCTS tree that has not been searched yet. Its first search is the
+ * same as ISMCTSReport.
+ *
+ * Returns:
+ *  An empty tree.
+ */
+func NewISMCTSTree() *ISMCTSTree {
+    return &ISMCTSTree{ NewISNode() }
+}
+
+
+/*
+ * Start the tree over, such as when a new game starts.
+ */
+func (t *ISMCTSTree) Reset() {
+    t.root = NewISNode()
+}
+
+
+/*
+ * Move the root of the tree along the given actions, in the order they were
+ * played. If the engine is a MergingEngine, each action is followed by the
+ * action that stands for it in the state it was played from, since the tree
+ * only has children for those.
+ *
+ * Args:
+ *  engine: The engine for the game logic.
+ *  states: For each action, a determinized state it could have been played
+ *          from.
+ *  actions: The actions that were played since the last search.
+ */
+func (t *ISMCTSTree) Advance(engine TSEngine, states []TSState,
+                             actions []interface{}) {
+    merging, merges := engine.(MergingEngine)
+    for i, action := range actions {
+        if merges {
+            action = merging.Representative(states[i], action)
+        }
+
+        var next *ISNode
+        for _, child := range t.root.children {
+            if child.action == action {
+                next = child
+            }
+        }
+
+        if next == nil {
+            t.Reset()
+            return
+        }
+
+        next.parent = nil
+        next.action = nil
+        t.root = next
+    }
+}
+
+
+/*
+ * Search from the given state with the tree that is kept, and give a report in
+ * the same way as ISMCTSReport. Only the iterations needed for the root to have
+ * the given number of visits are run. If the options can stop early, the root
+ * actions are checked every Interval iterations.
+ *
+ * Args:
+ *  s: The current state from which to start simulation.
+ *  engine: The game engine with which to step through game logic.
+ *  iterations: The number of visits the root should have.
+ *  opts: The options for the search.
+ *
+ * Returns:
+ *  The report of the search, with Playouts and Reused as for an MCTSForest.
+ *  Determinizations only counts the determinizations of this search.
+ */
+func (t *ISMCTSTree) Report(s State, engine TSEngine, iterations int,
+                            opts MCTSOptions) Report {
+    start := time.Now()
+    seen := make(map[interface{}]Move)
+    legal := make(map[interface{}]int)
+    reused := t.root.simulations
+    playouts := 0
+
+    limit := opts.Stop.limit(iterations)
+    interval := opts.Stop.interval(iterations)
+    separated := false
+    for i := reused; i < limit && !separated; i++ {
+        d := s.Copy()
+        d.Determinize()
+
+        // The root moves are remembered so that a full move can be returned
+        // when the search is done.
+        for _, move := range engine.Successors(d) {
+            seen[move.Action] = move
+            legal[move.Action]++
+        }
+
+        runISPlayout(t.root, d, engine, opts)
+        playouts++
+
+        // An action that was never tried could be the best.
+        if opts.Stop.enabled() && playouts % interval == 0 {
+            actions := t.stats(seen, legal)
+            separated = len(actions) == len(seen) &&
+                        Separated(actions, opts.Stop.Z, opts.Stop.MinVisits)
+        }
+    }
+
+    // Every root action needs a move even if no new determinization was drawn.
+    if len(seen) < len(t.root.children) {
+        d := s.Copy()
+        d.Determinize()
+        for _, move := range engine.Successors(d) {
+            if _, ok := seen[move.Action]; !ok {
+                seen[move.Action] = move
+            }
+        }
+    }
+
+    actions := t.stats(seen, legal)
+    report := newReport(actions, playouts, start, opts.Final)
+    report.Reused = reused
+    report.Separated = separated
+
+    return report
+}
+
+
+/*
+ * The statistics of the root actions of the tree.
+ *
+ * Args:
+ *  seen: A move for every root action that is legal in the current state.
+ *  legal: The number of determinizations each root action was legal in.
+ *
+ * Returns:
+ *  The statistics of every root action that is legal in the current state, in
+ *  the order they were added to the tree.
+ */
+func (t *ISMCTSTree) stats(seen map[interface{}]Move,
+                           legal map[interface{}]int) []ActionStats {
+    actions := make([]ActionStats, 0, len(t.root.children))
+    for _, child := range t.root.children {
+        move, ok := seen[child.action]
+        if !ok {
+            // An action that is not legal in the current state has nothing to
+            // report.
+            continue
+        }
+
+        totals := actionTotals{ determinizations: legal[child.action] }
+        totals.add(move, child.simulations, child.eval, child.sumSquares,
+                   child.min, child.max)
+        actions = append(actions, totals.stats())
+    }
+
+    return actions
+}
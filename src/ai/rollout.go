@@ -0,0 +1,118 @@
+package ai
+
+import (
+    "fmt"
+    "rng"
+)
+
+
+/*
+ * A rollout policy plays out the rest of a game once MCTS has added a new node
+ * to its tree. None of the states it passes through are added to the tree, so
+ * a policy can be as smart, or as fast, as is worth it.
+ */
+type RolloutPolicy interface {
+    /*
+     * Choose the next move in a playout.
+     *
+     * Args:
+     *  state: The current state of the playout. It is not terminal.
+     *  moves: The successors of the state. There is at least one.
+     *
+     * Returns:
+     *  The index of the chosen move in moves.
+     */
+    Choose(state TSState, moves []Move) int
+}
+
+
+/*
+ * A rollout policy that chooses every move uniformly at random.
+ */
+type RandomRollout struct { }
+
+
+func (p RandomRollout) Choose(state TSState, moves []Move) int {
+    return rng.Intn(len(moves))
+}
+
+
+/*
+ * A rollout policy that mixes another policy with random play. With probability
+ * Epsilon a move is chosen uniformly at random, and otherwise Policy chooses.
+ */
+type EpsilonRollout struct {
+    Epsilon float64
+    Policy RolloutPolicy
+}
+
+
+func (p EpsilonRollout) Choose(state TSState, moves []Move) int {
+    if rng.Float64() < p.Epsilon {
+        return rng.Intn(len(moves))
+    }
+
+    return p.Policy.Choose(state, moves)
+}
+
+
+/*
+ * Play a game out from the given state with a rollout policy.
+ *
+ * Args:
+ *  state: The state to start from.
+ *  engine: The engine for the game logic.
+ *  policy: The policy that chooses each move.
+ *
+ * Returns:
+ *  The evaluation of the terminal state that is reached.
+ */
+func Rollout(state TSState, engine TSEngine, policy RolloutPolicy) float64 {
+    return rollout(state, engine, policy, false, nil, searchCutoff{ }, 0)
+}
+
+
+/*
+ * The internal logic of a rollout. Provides a logging flag for debugging
+ * purposes.
+ *
+ * Args:
+ *  state: The state to start from.
+ *  engine: The engine for the game logic.
+ *  policy: The policy that chooses each move.
+ *  log: A flag to indicate whether each state should be printed.
+ *  steps: If not nil, every move of the rollout is added to it for AMAF.
+ *  cut: The cutoff of the search.
+ *  depth: The number of moves from the root of the search to state.
+ *
+ * Returns:
+ *  The evaluation of the terminal state that is reached, or the heuristic
+ *  score of the state that the cutoff stopped at.
+ */
+func rollout(state TSState, engine TSEngine, policy RolloutPolicy,
+             log bool, steps *[]amafStep, cut searchCutoff,
+             depth int) float64 {
+    for ; !engine.IsTerminal(state); depth++ {
+        if log {
+            fmt.Println(state)
+        }
+
+        if cut.stop(state, depth) {
+            return cut.heuristic(state)
+        }
+
+        moves := engine.Successors(state)
+        move := moves[policy.Choose(state, moves)]
+        if steps != nil {
+            *steps = append(*steps, amafStep{ move.Action,
+                                              engine.Favorable(state) })
+        }
+        state = move.State
+    }
+
+    if log {
+        fmt.Println(state)
+    }
+
+    return engine.Evaluation(state)
+}
@@ -21,3 +21,14 @@ type TSEngine interface {
     Evaluation(state TSState) float64
     Successors(state TSState) []Move
 }
+
+
+/*
+ * An engine whose successors stand for more than one action, such as one that
+ * merges equivalent moves, can say which of its actions stands for a given
+ * action. This lets a search follow an action that was played but is not one
+ * of the engine's successors.
+ */
+type MergingEngine interface {
+    Representative(state TSState, action interface{}) interface{}
+}
@@ -16,7 +16,8 @@ import (
  * the initial setup of a Euchre hand and then the difference between the
  * specified player type and the optimal, all knowing player. This script
  * calculates the average, and the distribution of the differences amongst the
- * values 0 to 6 inclusive.
+ * values 0 to 6 inclusive. Lines starting with # are comments, such as the
+ * seed of the run, and are skipped.
  *
  * Usage:
  *  ./benchmark_analysis -dataLoc={dataLoc}
@@ -40,6 +41,9 @@ func main() {
     scanner := bufio.NewScanner(dataFile)
     for scanner.Scan() {
         line := scanner.Text()
+        if strings.HasPrefix(line, "#") {
+            continue
+        }
         tabIndex := strings.IndexRune(line, '\t')
 
         diff, _ := strconv.ParseFloat(line[tabIndex + 1:], 64)
@@ -10,8 +10,10 @@ import (
     "fmt"
     "encoding/json"
     "log"
+    "math"
     "os"
     "player"
+    "rng"
     "strconv"
     "strings"
 )
@@ -26,7 +28,7 @@ import (
  * data sample.
  *
  * Usage:
- *  ./benchmark_play {dataFile} {playerType}
+ *  ./benchmark_play -dataLoc {dataFile} -playerType {playerType} -seed {seed}
  *
  * dataFile is the location of the minimax evaluated hands. playerType is the
  * type of player to run on these situations. The mapping from playerType to
@@ -34,6 +36,54 @@ import (
  *  0: MCTS
  *  1: RULE
  *  2: RANDOM
+ *  3: ISMCTS
+ *  4: PIMC
+ *  5: ALPHA-MU
+ *
+ * The MCTS based players use the play runs and determinizations given by the
+ * playRuns and playDeterminizations flags. ISMCTS runs the same total number
+ * of playouts as MCTS so that the two can be compared at an equal budget. PIMC
+ * solves each of its playDeterminizations exactly, so it ignores playRuns.
+ * Alpha-mu also ignores playRuns, and searches maxMoves of its own moves.
+ *
+ * The tree policy of MCTS and ISMCTS is chosen with the policy flag, which is
+ * one of ucb1, ucb1-tuned, puct or epsilon-greedy. policyParam is the
+ * exploration constant for ucb1 and puct, the variance bound for ucb1-tuned,
+ * and epsilon for epsilon-greedy. If it is not given, each policy uses its own
+ * default from policyDefaults. If rave is positive, AMAF statistics are
+ * tracked and blended into the chosen policy with rave as the RAVE constant.
+ * The move is picked from the root by the final flag, which is one of
+ * max-visits, max-mean or robust-max. maxNodes caps the nodes in each MCTS
+ * tree, with 0 for no cap, and recycle cuts off the least visited subtrees of
+ * a full tree rather than no longer expanding it. reuse keeps the search of
+ * MCTS and ISMCTS between the plays of a hand. merge gives equivalent cards a
+ * single successor in the searches of MCTS, ISMCTS and the Minimax opponents.
+ * If cutoffTricks is positive, MCTS and ISMCTS playouts stop after that many
+ * tricks and the rest of the hand is scored by the euchre heuristic.
+ *
+ * If stopZ is positive, MCTS and ISMCTS check the root every stopInterval
+ * playouts, and stop once the lower confidence bound of the best card, stopZ
+ * standard errors below its mean, beats the upper bound of every other card
+ * that has at least stopMinVisits visits. A close decision may run for up to
+ * extend times the play budget.
+ *
+ * If infer is set, MCTS and ISMCTS infer where the hidden cards are from the
+ * bidding and play with euchre.DefaultInference, and draw their
+ * determinizations from those beliefs.
+ *
+ * If weightModel is rule or random, MCTS weights each of its determinizations
+ * by how likely that player, asked weightSamples times a card, is to have
+ * played the cards the other players played. weightEpsilon is the chance of a
+ * card the model would not play, and reject keeps or throws away each deal
+ * with a chance of its likelihood instead.
+ *
+ * The rollout policy of MCTS and ISMCTS is chosen with the rollout flag, which
+ * is one of random, rule or mix. mix plays a random card with probability
+ * rolloutEpsilon and the rule player's card otherwise.
+ *
+ * seed is the seed for all randomness in the run. If it is not provided one is
+ * taken from the clock. Either way it is recorded on the first line of the
+ * output so that the run can be replayed exactly.
  */
 
 
@@ -56,21 +106,117 @@ func main() {
     var dataLoc string
     var playerType int
     var paired bool
+    var seed int64
+    var playRuns int
+    var playDeterminizations int
+    var maxMoves int
+    var policyName string
+    var policyParam float64
+    var rolloutName string
+    var rolloutEpsilon float64
+    var rave float64
+    var finalName string
+    var maxNodes int
+    var recycle bool
+    var reuse bool
+    var merge bool
+    var cutoffTricks int
+    var stopZ float64
+    var stopInterval int
+    var stopMinVisits int
+    var extend float64
+    var infer bool
+    var weightModel string
+    var weightSamples int
+    var weightEpsilon float64
+    var reject bool
     flag.StringVar(&dataLoc, "dataLoc", "", "Location of minimax evaluated games.")
     flag.IntVar(&playerType, "playerType", 0, "The type of player to evaluate.")
     flag.BoolVar(&paired, "paired", false, "Set if you wish partner play to be evaluated.")
+    flag.Int64Var(&seed, "seed", rng.GetSeed(), "The seed for all randomness in the run.")
+    flag.IntVar(&playRuns, "playRuns", PLAY_RUNS, "The runs per determinization for play.")
+    flag.IntVar(&playDeterminizations, "playDeterminizations", PLAY_DETERMINIZATIONS,
+                "The determinizations for play.")
+    flag.IntVar(&maxMoves, "maxMoves", 2, "The moves alpha-mu searches over all determinizations.")
+    flag.StringVar(&policyName, "policy", "ucb1", "The MCTS tree policy.")
+    flag.Float64Var(&policyParam, "policyParam", -1, "The parameter of the tree policy, or -1 for its default.")
+    flag.StringVar(&rolloutName, "rollout", "random", "The MCTS rollout policy.")
+    flag.Float64Var(&rolloutEpsilon, "rolloutEpsilon", 0.1, "The chance of a random card in a mix rollout.")
+    flag.Float64Var(&rave, "rave", 0, "The RAVE constant, or 0 to not use RAVE.")
+    flag.StringVar(&finalName, "final", "max-visits", "How MCTS picks the final move.")
+    flag.IntVar(&maxNodes, "maxNodes", 0, "The most nodes in an MCTS tree, or 0 for no cap.")
+    flag.BoolVar(&recycle, "recycle", false, "Set to recycle subtrees of a full MCTS tree.")
+    flag.BoolVar(&reuse, "reuse", false, "Set to keep the search between plays of a hand.")
+    flag.BoolVar(&merge, "merge", false, "Set to merge equivalent cards in searches.")
+    flag.IntVar(&cutoffTricks, "cutoffTricks", 0, "The tricks a playout plays before it is scored, or 0 for all.")
+    flag.Float64Var(&stopZ, "stopZ", 0, "The width of the bounds to stop early with, or 0 to not stop.")
+    flag.IntVar(&stopInterval, "stopInterval", 50, "The playouts per tree between checks to stop early.")
+    flag.IntVar(&stopMinVisits, "stopMinVisits", 100, "The visits every card needs to stop early.")
+    flag.Float64Var(&extend, "extend", 1, "The most a close decision may run, as a multiple of the budget.")
+    flag.BoolVar(&infer, "infer", false, "Set to infer where hidden cards are from the bidding and play.")
+    flag.StringVar(&weightModel, "weightModel", "none", "The player the other players are modeled as to weight determinizations.")
+    flag.IntVar(&weightSamples, "weightSamples", 5, "The times the model is asked about each card.")
+    flag.Float64Var(&weightEpsilon, "weightEpsilon", 0.1, "The chance of a card the model would not play.")
+    flag.BoolVar(&reject, "reject", false, "Set to reject deals rather than weight them.")
     flag.Parse()
 
+    rng.Seed(seed)
+    fmt.Printf("# seed: %d\n", seed)
+
     // Create the mapping of playerType to player object and get the desired
     // player to evaluate.
+    options := ai.DefaultMCTSOptions()
+    options.Policy = parsePolicy(policyName, policyParam)
+    options.Rollout = parseRollout(rolloutName, rolloutEpsilon)
+    options.Final = parseFinal(finalName)
+    options.MaxNodes = maxNodes
+    options.Recycle = recycle
+    options.Stop = ai.EarlyStop{ stopZ, stopInterval, stopMinVisits, extend }
+    if cutoffTricks > 0 {
+        options.Cutoff = euchre.TrickCutoff{ cutoffTricks }
+    }
+    var inference *euchre.Inference
+    if infer {
+        defaults := euchre.DefaultInference()
+        inference = &defaults
+    }
+    if rave > 0 {
+        options.Policy = ai.RAVE{ Policy: options.Policy, K: rave }
+        options.AMAF = true
+    }
+
     players := make(map[int]player.Player)
-    players[0] = player.NewSmart(PICKUP_CONF, CALL_CONF, ALONE_CONF,
-                                  PICKUP_RUNS, PICKUP_DETERMINIZATIONS,
-                                  CALL_RUNS, CALL_DETERMINIZATIONS,
-                                  PLAY_RUNS, PLAY_DETERMINIZATIONS,
-                                  ALONE_RUNS, ALONE_DETERMINIZATIONS)
+    mcts := player.NewSmart(PICKUP_CONF, CALL_CONF, ALONE_CONF,
+                            PICKUP_RUNS, PICKUP_DETERMINIZATIONS,
+                            CALL_RUNS, CALL_DETERMINIZATIONS,
+                            playRuns, playDeterminizations,
+                            ALONE_RUNS, ALONE_DETERMINIZATIONS)
+    mcts.SetOptions(options)
+    mcts.SetReuse(reuse)
+    mcts.SetEngine(euchre.Engine{ MergeEquivalent: merge })
+    mcts.SetInference(inference)
+    mcts.SetWeighting(parseWeighting(weightModel, weightSamples, weightEpsilon,
+                                     reject))
+    players[0] = mcts
     players[1] = player.NewRule("data/train.dat")
     players[2] = player.NewRand(0.5, 0.5, 0)
+    ismcts := player.NewSmart(PICKUP_CONF, CALL_CONF, ALONE_CONF,
+                              PICKUP_RUNS, PICKUP_DETERMINIZATIONS,
+                              CALL_RUNS, CALL_DETERMINIZATIONS,
+                              playRuns, playDeterminizations,
+                              ALONE_RUNS, ALONE_DETERMINIZATIONS)
+    ismcts.SetSearch(player.InformationSetSearch)
+    ismcts.SetOptions(options)
+    ismcts.SetReuse(reuse)
+    ismcts.SetEngine(euchre.Engine{ MergeEquivalent: merge })
+    ismcts.SetInference(inference)
+    players[3] = ismcts
+    players[4] = player.NewPIMC(PICKUP_CONF, CALL_CONF, ALONE_CONF,
+                                PICKUP_DETERMINIZATIONS, CALL_DETERMINIZATIONS,
+                                playDeterminizations, ALONE_DETERMINIZATIONS)
+    players[5] = player.NewAlphaMu(PICKUP_CONF, CALL_CONF, ALONE_CONF, maxMoves,
+                                   PICKUP_DETERMINIZATIONS, CALL_DETERMINIZATIONS,
+                                   playDeterminizations, ALONE_DETERMINIZATIONS)
     chosenPlayer := players[playerType]
 
     dataFile, err := os.Open(dataLoc)
@@ -84,6 +230,9 @@ func main() {
         // Parse each line to get the minimax evaluation, and the actual game
         // state.
         line := scanner.Text()
+        if strings.HasPrefix(line, "#") {
+            continue
+        }
         tabIndex := strings.IndexRune(line, '\t')
 
         var state euchre.State
@@ -94,7 +243,7 @@ func main() {
         // Now that we have the game state, we can simulate the game. Using
         // Minimax players for the opponents and the desired player strategy
         // for user 0.
-        engine := euchre.Engine{ }
+        engine := euchre.Engine{ MergeEquivalent: merge }
 
         for i := 0; i < 5; i++ {
 
@@ -152,3 +301,129 @@ func main() {
         log.Fatal(err)
     }
 }
+
+
+/*
+ * The parameter of each tree policy when none is given. Rewards are from -4 to
+ * 4, so the variance bound of ucb1-tuned is the largest variance such a reward
+ * can have, (4 - -4)^2 / 4.
+ */
+var policyDefaults = map[string]float64 {
+    "ucb1": math.Sqrt2,
+    "ucb1-tuned": 16,
+    "puct": math.Sqrt2,
+    "epsilon-greedy": 0.1,
+}
+
+
+/*
+ * Create the tree policy with the given name.
+ *
+ * Args:
+ *  name: The name of the policy. One of ucb1, ucb1-tuned, puct or
+ *        epsilon-greedy.
+ *  param: The parameter of the policy, or a negative number for its default.
+ *
+ * Returns:
+ *  The tree policy. The program exits if the name is not known.
+ */
+func parsePolicy(name string, param float64) ai.SelectionPolicy {
+    def, ok := policyDefaults[name]
+    if !ok {
+        log.Fatalf("Unknown tree policy %s.", name)
+    }
+    if param < 0 {
+        param = def
+    }
+
+    switch name {
+    case "ucb1":
+        return ai.UCB1{ C: param }
+    case "ucb1-tuned":
+        return ai.UCB1Tuned{ Bound: param }
+    case "puct":
+        return ai.PUCT{ C: param }
+    }
+
+    return ai.EpsilonGreedy{ Epsilon: param }
+}
+
+
+/*
+ * Create the rollout policy with the given name.
+ *
+ * Args:
+ *  name: The name of the rollout policy. One of random, rule or mix.
+ *  epsilon: The chance of a random card for the mix policy.
+ *
+ * Returns:
+ *  The rollout policy. The program exits if the name is not known.
+ */
+func parseRollout(name string, epsilon float64) ai.RolloutPolicy {
+    switch name {
+    case "random":
+        return ai.RandomRollout{ }
+    case "rule":
+        return player.NewRuleRollout()
+    case "mix":
+        return ai.EpsilonRollout{ Epsilon: epsilon,
+                                  Policy: player.NewRuleRollout() }
+    }
+
+    log.Fatalf("Unknown rollout policy %s.", name)
+    return nil
+}
+
+
+/*
+ * Find how to weight determinizations by a model of the other players.
+ *
+ * Args:
+ *  model: The name of the model. One of none, rule or random.
+ *  samples: The times the model is asked about each card.
+ *  epsilon: The chance of a card the model would not play.
+ *  reject: Whether to reject deals rather than weight them.
+ *
+ * Returns:
+ *  The weighting, or nil for none. The program exits if the name is not known.
+ */
+func parseWeighting(model string, samples int, epsilon float64,
+                    reject bool) *player.PolicyWeighting {
+    switch model {
+    case "none":
+        return nil
+    case "rule":
+        return &player.PolicyWeighting{ player.NewRule(""), samples, epsilon,
+                                        reject }
+    case "random":
+        return &player.PolicyWeighting{ player.NewRand(0.5, 0.5, 0), samples,
+                                        epsilon, reject }
+    }
+
+    log.Fatalf("Unknown weighting model %s.", model)
+    return nil
+}
+
+
+/*
+ * Find the final selection strategy with the given name.
+ *
+ * Args:
+ *  name: The name of the strategy. One of max-visits, max-mean or robust-max.
+ *
+ * Returns:
+ *  The final selection strategy. The program exits if the name is not known.
+ */
+func parseFinal(name string) ai.FinalSelection {
+    switch name {
+    case "max-visits":
+        return ai.MaxVisits
+    case "max-mean":
+        return ai.MaxMean
+    case "robust-max":
+        return ai.RobustMax
+    }
+
+    log.Fatalf("Unknown final selection %s.", name)
+    return ai.MaxVisits
+}
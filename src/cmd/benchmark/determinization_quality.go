@@ -0,0 +1,220 @@
+package main
+
+
+import (
+    "ai"
+    "bufio"
+    "deck"
+    "euchre"
+    "flag"
+    "fmt"
+    "encoding/json"
+    "log"
+    "os"
+    "player"
+    "rng"
+    "strings"
+)
+
+
+/*
+ * Measure how much probability the determinizer gives to where the cards
+ * really are. Each recorded situation is a full deal, which is played out by
+ * the play player for every seat. Before every card, the player to move
+ * determinizes from their own point of view, the share of the determinizations
+ * that put each hidden card with each holder is taken as their beliefs, and
+ * those beliefs are scored against the real deal with euchre.Quality.
+ *
+ * Usage:
+ *  ./determinization_quality -dataLoc {dataFile} -determinizations {n}
+ *
+ * For each trick, and then for the whole hand, the output has the number of
+ * hidden cards scored, their average log-likelihood, and the calibration
+ * error. A higher log-likelihood and a lower calibration error are better.
+ * After that is the calibration of every bin, with the average probability in
+ * the bin and how often those holders really had the card. Probabilities are
+ * raised to at least floor before they are logged.
+ *
+ * play is one of rule, random or minimax. If observer is a seat from 0 to 3,
+ * only the plays of that seat are scored. infer and the weight flags pick the
+ * determinizer as in benchmark_play, where weightModel is one of none, rule
+ * or random.
+ */
+
+
+func main() {
+    var dataLoc string
+    var seed int64
+    var determinizations int
+    var playName string
+    var observer int
+    var bins int
+    var floor float64
+    var infer bool
+    var weightModel string
+    var weightSamples int
+    var weightEpsilon float64
+    var reject bool
+    flag.StringVar(&dataLoc, "dataLoc", "", "Location of the recorded situations.")
+    flag.Int64Var(&seed, "seed", rng.GetSeed(), "The seed for all randomness in the run.")
+    flag.IntVar(&determinizations, "determinizations", 50, "The determinizations for each play.")
+    flag.StringVar(&playName, "play", "rule", "The player that plays out each situation.")
+    flag.IntVar(&observer, "observer", -1, "The only seat to score, or -1 for every seat.")
+    flag.IntVar(&bins, "bins", 10, "The number of calibration bins.")
+    flag.Float64Var(&floor, "floor", 0.001, "The least probability a card is scored with.")
+    flag.BoolVar(&infer, "infer", false, "Set to infer where hidden cards are from the bidding and play.")
+    flag.StringVar(&weightModel, "weightModel", "none", "The player the other players are modeled as to weight determinizations.")
+    flag.IntVar(&weightSamples, "weightSamples", 5, "The times the model is asked about each card.")
+    flag.Float64Var(&weightEpsilon, "weightEpsilon", 0.1, "The chance of a card the model would not play.")
+    flag.BoolVar(&reject, "reject", false, "Set to reject deals rather than weight them.")
+    flag.Parse()
+
+    rng.Seed(seed)
+    fmt.Printf("# seed: %d\n", seed)
+
+    inference := euchre.DefaultInference()
+    var weighting *player.PolicyWeighting
+    if weightModel != "none" {
+        weighting = &player.PolicyWeighting{ parseModel(weightModel),
+                                             weightSamples, weightEpsilon,
+                                             reject }
+    }
+    var play player.Player
+    if playName != "minimax" {
+        play = parseModel(playName)
+    }
+
+    dataFile, err := os.Open(dataLoc)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer dataFile.Close()
+
+    total := euchre.NewQuality(bins, floor)
+    tricks := make([]*euchre.Quality, 5)
+    for i := range tricks {
+        tricks[i] = euchre.NewQuality(bins, floor)
+    }
+
+    engine := euchre.Engine{ }
+    scanner := bufio.NewScanner(dataFile)
+    for scanner.Scan() {
+        // The situation is everything before the tab, if there is one.
+        line := scanner.Text()
+        if strings.HasPrefix(line, "#") {
+            continue
+        }
+        if tabIndex := strings.IndexRune(line, '\t'); tabIndex >= 0 {
+            line = line[:tabIndex]
+        }
+
+        var state euchre.State
+        json.Unmarshal([]byte(line), &state)
+
+        for !engine.IsTerminal(state) {
+            seat := state.Player
+            if observer < 0 || seat == observer {
+                hand := append([]deck.Card{ }, state.Hands[seat]...)
+                observed := euchre.NewObservedState(state.Setup, seat, seat,
+                                                    hand, state.Played,
+                                                    state.Prior, nil)
+                if infer {
+                    observed.Beliefs = euchre.Infer(observed, inference)
+                }
+
+                var deals []ai.Determinization
+                if weighting != nil {
+                    deals = weighting.Determinizations(observed,
+                                                       determinizations)
+                } else {
+                    deals = ai.Worlds(observed, determinizations)
+                }
+
+                beliefs := euchre.DealBeliefs(deals)
+                tricks[len(state.Prior)].Score(observed, beliefs, state)
+                total.Score(observed, beliefs, state)
+            }
+
+            state = playCard(state, play, engine)
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        log.Fatal(err)
+    }
+
+    fmt.Printf("# trick\tcards\tlog-likelihood\tcalibration error\n")
+    for i, q := range tricks {
+        fmt.Printf("%d\t%d\t%f\t%f\n", i + 1, q.Cards, q.MeanLogLikelihood(),
+                   q.CalibrationError())
+    }
+    fmt.Printf("all\t%d\t%f\t%f\n", total.Cards, total.MeanLogLikelihood(),
+               total.CalibrationError())
+
+    fmt.Printf("# bin\tpairs\tpredicted\tobserved\n")
+    for bin := range total.Pairs {
+        predicted, observed := 0.0, 0.0
+        if pairs := float64(total.Pairs[bin]); pairs > 0 {
+            predicted = total.Predicted[bin] / pairs
+            observed = total.Hits[bin] / pairs
+        }
+        fmt.Printf("%.2f-%.2f\t%d\t%f\t%f\n", float64(bin) / float64(bins),
+                   float64(bin + 1) / float64(bins), total.Pairs[bin],
+                   predicted, observed)
+    }
+}
+
+
+/*
+ * Create the player with the given name.
+ *
+ * Args:
+ *  name: The name of the player. One of rule or random.
+ *
+ * Returns:
+ *  The player. The program exits if the name is not known.
+ */
+func parseModel(name string) player.Player {
+    switch name {
+    case "rule":
+        return player.NewRule("")
+    case "random":
+        return player.NewRand(0.5, 0.5, 0)
+    }
+
+    log.Fatalf("Unknown player %s.", name)
+    return nil
+}
+
+
+/*
+ * Play the next card of a full deal.
+ *
+ * Args:
+ *  state: The determinized state to play from.
+ *  play: The player who picks the card, or nil for Minimax.
+ *  engine: The engine to play with.
+ *
+ * Returns:
+ *  The state after the card is played.
+ */
+func playCard(state euchre.State, play player.Player,
+              engine euchre.Engine) euchre.State {
+    if play == nil {
+        _, move := ai.Minimax(state, engine)
+        return move.State.(euchre.State)
+    }
+
+    // Players remove the card they play from the hand, so give them a copy.
+    hand := append([]deck.Card{ }, state.Hands[state.Player]...)
+    _, chosen := play.Play(state.Player, state.Setup, hand, state.Played,
+                           state.Prior)
+    for _, move := range engine.Successors(state) {
+        if move.Action.(deck.Card) == chosen {
+            return move.State.(euchre.State)
+        }
+    }
+
+    log.Fatalf("%v is not a legal play.", chosen)
+    return state
+}
@@ -0,0 +1,105 @@
+package main
+
+
+import (
+    "ai"
+    "bufio"
+    "euchre"
+    "flag"
+    "fmt"
+    "encoding/json"
+    "log"
+    "os"
+    "rng"
+    "strings"
+)
+
+
+/*
+ * Export the MCTS tree that the player to move would build in a recorded
+ * situation, so that a surprising play can be inspected. The situation is a
+ * line of a benchmark data file, such as those made by gen_benchmark_play. The
+ * tree is of a single determinization of what the player to move can see.
+ *
+ * Usage:
+ *  ./export_tree -dataLoc {dataFile} -line {n} -format {dot|json}
+ *
+ * line is the index of the situation in the data file, not counting comment
+ * lines. runs is the number of playouts in the tree. depth and minVisits limit
+ * the nodes that are written, and a depth of 0 writes the whole tree. The tree
+ * is written to standard output, so a DOT tree can be piped to dot -Tsvg.
+ */
+
+
+func main() {
+    var dataLoc string
+    var line int
+    var format string
+    var runs int
+    var depth int
+    var minVisits int
+    var seed int64
+    flag.StringVar(&dataLoc, "dataLoc", "", "Location of the recorded situations.")
+    flag.IntVar(&line, "line", 0, "The index of the situation to search.")
+    flag.StringVar(&format, "format", "dot", "The output format, dot or json.")
+    flag.IntVar(&runs, "runs", 5000, "The playouts to run in the tree.")
+    flag.IntVar(&depth, "depth", 2, "The deepest level of the tree to write, or 0 for all.")
+    flag.IntVar(&minVisits, "minVisits", 1, "The fewest visits a written node can have.")
+    flag.Int64Var(&seed, "seed", rng.GetSeed(), "The seed for all randomness in the run.")
+    flag.Parse()
+
+    rng.Seed(seed)
+
+    dataFile, err := os.Open(dataLoc)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer dataFile.Close()
+
+    var state euchre.State
+    found := false
+    index := 0
+    scanner := bufio.NewScanner(dataFile)
+    for scanner.Scan() && !found {
+        text := scanner.Text()
+        if strings.HasPrefix(text, "#") {
+            continue
+        }
+
+        if index == line {
+            stateStr := strings.SplitN(text, "\t", 2)[0]
+            if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+                log.Fatal(err)
+            }
+            found = true
+        }
+        index++
+    }
+
+    if err := scanner.Err(); err != nil {
+        log.Fatal(err)
+    }
+    if !found {
+        log.Fatalf("There are only %d situations in %s.", index, dataLoc)
+    }
+
+    // The tree is searched from the point of view of the player to move.
+    s := euchre.NewObservedState(state.Setup, state.Player, state.Player,
+                                 state.Hands[state.Player], state.Played,
+                                 state.Prior, nil)
+    root := ai.SearchTree(s, euchre.Engine{ }, runs, ai.DefaultMCTSOptions())
+    limits := ai.ExportLimits{ MaxDepth: depth, MinVisits: minVisits }
+
+    switch format {
+    case "dot":
+        err = ai.WriteDOT(os.Stdout, root, limits)
+    case "json":
+        err = ai.WriteJSON(os.Stdout, root, limits)
+    default:
+        err = fmt.Errorf("Unknown format %s.", format)
+    }
+
+    if err != nil {
+        log.Fatal(err)
+    }
+}
@@ -8,8 +8,7 @@ import (
     "euchre"
     "flag"
     "fmt"
-    "math/rand"
-    "time"
+    "rng"
 )
 
 
@@ -21,15 +20,14 @@ import (
  * to evaluate actual game play, not pre game play.
  *
  * Usage:
- *  ./gen_benchmark_play {samples} > data.txt
+ *  ./gen_benchmark_play {samples} {seed} > data.txt
  *
- * samples are the number of situations you wish to compare.
+ * samples are the number of situations you wish to compare. seed is the seed
+ * for generating the situations, which is taken from the clock if not given.
+ * The seed is recorded on the first line of the output.
  */
 
 
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
-
-
 /*
  * Create a random setup given the current situation. The random setup randomly
  * chooses who dealt and what trump is. Nobody is ever going alone though, and
@@ -43,11 +41,11 @@ var r = rand.New(rand.NewSource(time.Now().UnixNano()))
  *  The randomized euchre setup.
  */
 func randomNoAloneNoPickupSetup(splits [][]deck.Card) euchre.Setup {
-    dealer := r.Intn(4)
-    caller := r.Intn(4)
+    dealer := rng.Intn(4)
+    caller := rng.Intn(4)
     pickedUp := false
     top := splits[4][3]
-    trump := deck.SUITS[r.Intn(len(deck.SUITS))]
+    trump := deck.SUITS[rng.Intn(len(deck.SUITS))]
     var discard deck.Card
 
     return euchre.Setup {
@@ -64,9 +62,14 @@ func randomNoAloneNoPickupSetup(splits [][]deck.Card) euchre.Setup {
 
 func main() {
     var samples int
+    var seed int64
     flag.IntVar(&samples, "samples", 0, "Number of sample games to simluate")
+    flag.Int64Var(&seed, "seed", rng.GetSeed(), "The seed for generating samples.")
     flag.Parse()
 
+    rng.Seed(seed)
+    fmt.Printf("# seed: %d\n", seed)
+
     engine := euchre.Engine{ }
     for i := 0; i < samples; i++ {
         splits := euchre.GenSituation()
@@ -0,0 +1,116 @@
+package main
+
+
+import (
+    "ai"
+    "bufio"
+    "euchre"
+    "flag"
+    "fmt"
+    "encoding/json"
+    "log"
+    "os"
+    "rng"
+    "runtime"
+    "strings"
+    "time"
+)
+
+
+/*
+ * Measure the memory and time that a single MCTS tree takes at large budgets.
+ * The tree is built for the player to move in the first situation of a data
+ * file, for each of the given numbers of playouts. The memory is the growth of
+ * the live heap while the tree is held, after a garbage collection. maxNodes
+ * and recycle cap the tree as in the MCTS options, and the number of times the
+ * tree was recycled is given with the rest.
+ *
+ * Usage:
+ *  ./tree_memory -dataLoc {dataFile} -runs 10000,100000 -maxNodes {n}
+ */
+
+
+func main() {
+    var dataLoc string
+    var runsList string
+    var seed int64
+    var maxNodes int
+    var recycle bool
+    flag.StringVar(&dataLoc, "dataLoc", "", "Location of the recorded situations.")
+    flag.StringVar(&runsList, "runs", "10000,100000", "Comma separated playout budgets.")
+    flag.Int64Var(&seed, "seed", 1, "The seed for all randomness in the run.")
+    flag.IntVar(&maxNodes, "maxNodes", 0, "The most nodes in the tree, or 0 for no cap.")
+    flag.BoolVar(&recycle, "recycle", false, "Set to recycle subtrees of a full tree.")
+    flag.Parse()
+
+    dataFile, err := os.Open(dataLoc)
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer dataFile.Close()
+
+    var state euchre.State
+    scanner := bufio.NewScanner(dataFile)
+    for scanner.Scan() {
+        text := scanner.Text()
+        if !strings.HasPrefix(text, "#") {
+            stateStr := strings.SplitN(text, "\t", 2)[0]
+            if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+                log.Fatal(err)
+            }
+            break
+        }
+    }
+
+    s := euchre.NewObservedState(state.Setup, state.Player, state.Player,
+                                 state.Hands[state.Player], state.Played,
+                                 state.Prior, nil)
+
+    opts := ai.DefaultMCTSOptions()
+    opts.MaxNodes = maxNodes
+    opts.Recycle = recycle
+
+    fmt.Println("runs\tnodes\theap (MB)\tbytes/node\ttime (s)\trecycles")
+    for _, field := range strings.Split(runsList, ",") {
+        var runs int
+        fmt.Sscan(field, &runs)
+        rng.Seed(seed)
+
+        var before, after runtime.MemStats
+        runtime.GC()
+        runtime.ReadMemStats(&before)
+
+        start := time.Now()
+        root := ai.SearchTree(s, euchre.Engine{ }, runs, opts)
+        elapsed := time.Since(start)
+
+        runtime.GC()
+        runtime.ReadMemStats(&after)
+        heap := float64(after.HeapAlloc) - float64(before.HeapAlloc)
+
+        nodes := countNodes(ai.ExportTree(root, ai.ExportLimits{ }))
+        fmt.Printf("%d\t%d\t%.1f\t%.0f\t%.2f\t%d\n", runs, nodes,
+                   heap / 1e6, heap / float64(nodes), elapsed.Seconds(),
+                   root.Recycles())
+        runtime.KeepAlive(root)
+    }
+}
+
+
+/*
+ * Count the nodes of an exported tree.
+ *
+ * Args:
+ *  node: The root of the exported tree.
+ *
+ * Returns:
+ *  The number of nodes in the tree, including the root.
+ */
+func countNodes(node *ai.ExportedNode) int {
+    count := 1
+    for _, child := range node.Children {
+        count += countNodes(child)
+    }
+
+    return count
+}
@@ -9,6 +9,7 @@ import (
     "euchre"
     "player"
     "os"
+    "rng"
     "runtime/pprof"
 )
 
@@ -27,6 +28,8 @@ const (
 )
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to file")
+var seed = flag.Int64("seed", rng.GetSeed(), "seed for all randomness")
+var odds = flag.Bool("odds", false, "show where the unseen cards are before each play")
 
 func inputValidCard() deck.Card {
     var cardStr string
@@ -82,6 +85,8 @@ func main() {
     fmt.Println()
 
     flag.Parse()
+    rng.Seed(*seed)
+    fmt.Printf("Using seed %d.\n", *seed)
     if *cpuprofile != "" {
         f, err := os.Create(*cpuprofile)
         if err != nil {
@@ -207,6 +212,11 @@ func main() {
             played = append(played, card)
         }
 
+        if *odds {
+            printOdds(euchre.NewUndeterminizedState(setup, 0, curHand, played,
+                                                    prior))
+        }
+
         curHand, chosen = player.Play(0, setup, curHand, played, prior)
         played = append(played, chosen)
 
@@ -240,3 +250,26 @@ func main() {
         led = euchre.Winner(played, trump, led, alonePlayer)
     }
 }
+
+func printOdds(state euchre.State) {
+    marginals := euchre.Marginals(state)
+    if marginals == nil {
+        fmt.Println("No deal fits the cards played.")
+        return
+    }
+
+    fmt.Println("Card\tLeft\tPartner\tRight\tKitty")
+    for _, card := range deck.CARDS {
+        row := make([]float64, euchre.OutOfPlay + 1)
+        certain := false
+        for holder := range row {
+            row[holder] = marginals.Probability(card, holder)
+            certain = certain || row[holder] == 1
+        }
+
+        if !certain {
+            fmt.Printf("%s\t%.0f%%\t%.0f%%\t%.0f%%\t%.0f%%\n", card,
+                       row[1] * 100, row[2] * 100, row[3] * 100, row[4] * 100)
+        }
+    }
+}
@@ -3,11 +3,11 @@ package main
 import (
     "deck"
     "fmt"
-    "math/rand"
+    "log"
     "os"
     "player"
+    "rng"
     "strconv"
-    "time"
 )
 
 
@@ -18,11 +18,12 @@ import (
  * percentage is the chance a given hand will be evaluated.
  *
  * Usage:
- *  ./run_pickup {playerType} {samples}
+ *  ./run_pickup {playerType} {samples} [seed]
  *
  * playerType corresponds to the approach that will be ran. For a random player
  * use 1, for a rule based player use 2, for the supposedly smart approach use
- * 3. samples corresponds to how many different hands will be ran.
+ * 3. samples corresponds to how many different hands will be ran. seed is
+ * optional and seeds all randomness in the run. The seed used is printed first.
  */
 
 
@@ -43,7 +44,15 @@ const (
 
 
 func main() {
-    r := rand.New(rand.NewSource(time.Now().UnixNano()))
+    // Seed before any player is created so the whole run is reproducible.
+    if len(os.Args) > 3 {
+        seed, err := strconv.ParseInt(os.Args[3], 10, 64)
+        if err != nil {
+            log.Fatal(err)
+        }
+        rng.Seed(seed)
+    }
+    fmt.Printf("# seed: %d\n", rng.GetSeed())
 
     players := make(map[int]player.Player)
     players[0] = player.NewRand(0.5, 0.5, 0)
@@ -69,7 +78,7 @@ func main() {
         copyHand := make([]deck.Card, len(hand))
         copy(copyHand, hand)
 
-        dealer := r.Intn(4)
+        dealer := rng.Intn(4)
         pickup := player.Pickup(copyHand, top, dealer)
 
         fmt.Printf("%v\t%s\t%d\t%t\n", hand, top, dealer, pickup)
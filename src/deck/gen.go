@@ -1,11 +1,6 @@
 package deck
 
-import (
-    "math/rand"
-    "time"
-)
-
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
+import "rng"
 
 /*
  * This package interacts with the deck definitions in order to generate cards,
@@ -21,8 +16,8 @@ var r = rand.New(rand.NewSource(time.Now().UnixNano()))
  */
 func Draw() Card {
     var card Card
-    card.Suit = SUITS[r.Intn(4)]
-    card.Value = VALUES[r.Intn(6)]
+    card.Suit = SUITS[rng.Intn(4)]
+    card.Value = VALUES[rng.Intn(6)]
 
     return card
 }
@@ -39,7 +34,7 @@ func Draw() Card {
  */
 func DrawN(n int) []Card {
     hand := make([]Card, n)
-    perm := r.Perm(len(CARDS))
+    perm := rng.Perm(len(CARDS))
 
     for i := 0; i < n; i++ {
         hand[i] = CARDS[perm[i]]
@@ -1,8 +1,13 @@
 package euchre
 
 import (
+    "ai"
     "deck"
     "fmt"
+    "math"
+    "reflect"
+    "rng"
+    "sort"
     "testing"
 )
 
@@ -188,3 +193,649 @@ func TestDeterminizationProblematic(t *testing.T) {
         t.Errorf("Incorrect number of cards in some players hand.\n")
     }
 }
+
+
+/*
+ * Test that two determinizations from the same seed give the exact same hands,
+ * so that any run can be replayed.
+ */
+func TestDeterminizationReproducible(t *testing.T) {
+    setup := Setup {
+        1,
+        2,
+        false,
+        deck.Card { deck.C, deck.A },
+        deck.S,
+        deck.Card { },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.A },
+        deck.Card { deck.D, deck.Nine },
+        deck.Card { deck.D, deck.A },
+        deck.Card { deck.C, deck.Ten },
+        deck.Card { deck.S, deck.J },
+    }
+
+    var played []deck.Card
+    var prior []Trick
+
+    determinize := func() State {
+        rng.Seed(42)
+        state := NewUndeterminizedState(setup, 2, hand, played, prior)
+        state.Determinize()
+        return state
+    }
+
+    first := determinize()
+    for i := 0; i < 10; i++ {
+        next := determinize()
+        if !reflect.DeepEqual(first.Hands, next.Hands) {
+            t.Errorf("Gave %v and %v for the same seed.\n", first.Hands,
+                     next.Hands)
+        }
+    }
+}
+
+
+/*
+ * Test that every deal of a late hand is listed once, that each one keeps to
+ * what player 0 knows, and that the true deal and random determinizations are
+ * among them.
+ */
+func TestDeterminizations(t *testing.T) {
+    rng.Seed(3)
+    e := Engine{ }
+
+    for i := 0; i < 5; i++ {
+        hands := GenSituation()
+        setup := Setup {
+            3,
+            i % 4,
+            false,
+            hands[4][0],
+            deck.SUITS[i % 4],
+            deck.Card{ },
+            -1,
+        }
+
+        // Play random cards until the fourth trick has started.
+        var state ai.TSState = NewDeterminizedState(setup, 0, hands,
+                                                    []deck.Card{ }, []Trick{ })
+        for len(state.(State).Prior) < 3 || len(state.(State).Played) < 1 {
+            moves := e.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+        real := state.(State)
+        known := NewUndeterminizedState(setup, real.Player, real.Hands[0],
+                                        real.Played, real.Prior)
+
+        deals := known.Determinizations(100000)
+        if len(deals) == 0 || known.Determinizations(len(deals) - 1) != nil {
+            t.Fatalf("Gave %d deals, or a list that was too long.\n",
+                     len(deals))
+        }
+
+        voids := noSuits(real.Prior, setup.Trump)
+        total := 0.0
+        seen := make(map[string]bool)
+        for _, deal := range deals {
+            d := deal.State.(State)
+            total += deal.Weight
+            seen[fmt.Sprint(sortedHands(d.Hands))] = true
+
+            for player := 1; player < 4; player++ {
+                if len(d.Hands[player]) != len(real.Hands[player]) {
+                    t.Errorf("Player %d has %v instead of %d cards.\n",
+                             player, d.Hands[player], len(real.Hands[player]))
+                }
+
+                for _, card := range d.Hands[player] {
+                    if hasSuit(voids[player], card.AdjSuit(setup.Trump)) {
+                        t.Errorf("Player %d is out of the suit of %v.\n",
+                                 player, card)
+                    }
+                }
+            }
+        }
+
+        if len(seen) != len(deals) || math.Abs(total - 1) > 1e-9 {
+            t.Errorf("%d of %d deals are distinct with a total weight of " +
+                     "%f.\n", len(seen), len(deals), total)
+        }
+
+        if !seen[fmt.Sprint(sortedHands(real.Hands))] {
+            t.Errorf("The true deal %v is not listed.\n", real.Hands)
+        }
+
+        for j := 0; j < 10; j++ {
+            d := known.Copy().(State)
+            d.Determinize()
+            if !seen[fmt.Sprint(sortedHands(d.Hands))] {
+                t.Errorf("The determinization %v is not listed.\n", d.Hands)
+            }
+        }
+    }
+}
+
+
+/*
+ * Test that every seat can determinize from its own point of view, keeping to
+ * the cards it knows are in another hand, in the kitty or in play.
+ */
+func TestObservedDeterminizations(t *testing.T) {
+    rng.Seed(8)
+    e := Engine{ }
+
+    for observer := 0; observer < 4; observer++ {
+        hands := GenSituation()
+        setup := Setup {
+            (observer + 1) % 4,
+            observer,
+            false,
+            hands[4][0],
+            deck.SUITS[observer],
+            deck.Card{ },
+            -1,
+        }
+
+        var state ai.TSState = NewDeterminizedState(setup, 0, hands,
+                                                    []deck.Card{ }, []Trick{ })
+        for len(state.(State).Prior) < 3 || len(state.(State).Played) < 1 {
+            moves := e.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+        real := state.(State)
+
+        next := (observer + 1) % 4
+        across := (observer + 2) % 4
+        held := real.Hands[next][0]
+        kitty := hands[4][1]
+        exposed := real.Hands[across][0]
+        known := &Knowledge{ Kitty: []deck.Card{ kitty },
+                             Exposed: []deck.Card{ exposed } }
+        known.Held[next] = []deck.Card{ held }
+
+        observed := NewObservedState(setup, observer, real.Player,
+                                     real.Hands[observer], real.Played,
+                                     real.Prior, known)
+        deals := observed.Determinizations(100000)
+        if len(deals) == 0 {
+            t.Fatalf("Gave no deals for observer %d.\n", observer)
+        }
+
+        seen := make(map[string]bool)
+        for _, deal := range deals {
+            d := deal.State.(State)
+            seen[fmt.Sprint(sortedHands(d.Hands))] = true
+
+            if !reflect.DeepEqual(d.Hands[observer], real.Hands[observer]) ||
+               !hasCard(d.Hands[next], held) {
+                t.Errorf("Observer %d dealt %v.\n", observer, d.Hands)
+            }
+
+            inHand := false
+            for player, hand := range d.Hands {
+                if len(hand) != len(real.Hands[player]) ||
+                   hasCard(hand, kitty) != (player == OutOfPlay) {
+                    t.Errorf("Observer %d dealt %v.\n", observer, d.Hands)
+                }
+                if player != OutOfPlay {
+                    inHand = inHand || hasCard(hand, exposed)
+                }
+            }
+            if !inHand {
+                t.Errorf("Observer %d left %v out of play.\n", observer,
+                         exposed)
+            }
+        }
+
+        if !seen[fmt.Sprint(sortedHands(real.Hands))] {
+            t.Errorf("The true deal %v is not listed.\n", real.Hands)
+        }
+
+        for j := 0; j < 10; j++ {
+            d := observed.Copy().(State)
+            d.Determinize()
+            if !seen[fmt.Sprint(sortedHands(d.Hands))] {
+                t.Errorf("The determinization %v is not listed.\n", d.Hands)
+            }
+        }
+    }
+}
+
+
+/*
+ * Test that determinizations deal every card of the deck, with the kitty as its
+ * own hand holding the cards the observer knows are in it.
+ */
+func TestDeterminizedKitty(t *testing.T) {
+    rng.Seed(5)
+
+    tests := []struct {
+        pickedUp bool
+        seen bool
+    } {
+        { false, false },
+        { true, false },
+        { true, true },
+    }
+
+    for i, test := range tests {
+        hands := GenSituation()
+        top := hands[4][0]
+        hand := append([]deck.Card{ }, hands[0]...)
+        setup := Setup {
+            0,
+            1,
+            test.pickedUp,
+            top,
+            top.Suit,
+            deck.Card{ },
+            -1,
+        }
+
+        // The observer is the dealer, so they know their discard, and in some
+        // variants they also look at the rest of the kitty.
+        want := []deck.Card{ top }
+        if test.pickedUp {
+            setup.Discard = hand[0]
+            hand[0] = top
+            want[0] = setup.Discard
+        }
+        known := &Knowledge{ }
+        if test.seen {
+            known.Kitty = hands[4][1:]
+            want = append(want, hands[4][1:]...)
+        }
+
+        state := NewObservedState(setup, 0, 1, hand, []deck.Card{ },
+                                  []Trick{ }, known)
+        for j := 0; j < 10; j++ {
+            d := state.Copy().(State)
+            d.Determinize()
+
+            dealt := make(map[deck.Card]bool)
+            for _, h := range d.Hands {
+                for _, card := range h {
+                    dealt[card] = true
+                }
+            }
+
+            kitty := d.Hands[OutOfPlay]
+            if len(dealt) != 24 || len(kitty) != 4 {
+                t.Errorf("Case %d dealt %d cards with a kitty of %v.\n", i,
+                         len(dealt), kitty)
+            }
+
+            for _, card := range want {
+                if !hasCard(kitty, card) {
+                    t.Errorf("Case %d left %v out of the kitty %v.\n", i,
+                             card, kitty)
+                }
+            }
+        }
+    }
+}
+
+
+/*
+ * Test that beliefs that put every card where it really is score perfectly,
+ * and that the beliefs of every deal of a late hand give each hidden card one
+ * hit and a total probability of 1.
+ */
+func TestQuality(t *testing.T) {
+    rng.Seed(4)
+    e := Engine{ }
+
+    for i := 0; i < 5; i++ {
+        hands := GenSituation()
+        setup := Setup {
+            i % 4,
+            (i + 1) % 4,
+            false,
+            hands[4][0],
+            deck.SUITS[i % 4],
+            deck.Card{ },
+            -1,
+        }
+
+        var state ai.TSState = NewDeterminizedState(setup, 0, hands,
+                                                    []deck.Card{ }, []Trick{ })
+        for len(state.(State).Prior) < 3 {
+            moves := e.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+        real := state.(State)
+        observed := NewObservedState(setup, real.Player, real.Player,
+                                     real.Hands[real.Player], real.Played,
+                                     real.Prior, nil)
+        hidden := len(observed.Copy().(State).unknownCards())
+
+        truth := NewQuality(10, 0.001)
+        truth.Score(observed, DealBeliefs([]ai.Determinization{ { real, 1 } }),
+                    real)
+        if truth.Cards != hidden || truth.MeanLogLikelihood() != 0 ||
+           truth.CalibrationError() != 0 {
+            t.Errorf("The real deal scored %d cards at %f with an error of " +
+                     "%f.\n", truth.Cards, truth.MeanLogLikelihood(),
+                     truth.CalibrationError())
+        }
+
+        q := NewQuality(10, 0.001)
+        q.Score(observed, DealBeliefs(observed.Determinizations(100000)), real)
+
+        hits, predicted := 0.0, 0.0
+        for bin := range q.Hits {
+            hits += q.Hits[bin]
+            predicted += q.Predicted[bin]
+        }
+
+        if q.Cards != hidden || hits != float64(hidden) ||
+           math.Abs(predicted - float64(hidden)) > 1e-9 {
+            t.Errorf("Scored %d of %d cards with %f hits and %f predicted.\n",
+                     q.Cards, hidden, hits, predicted)
+        }
+
+        if ll := q.MeanLogLikelihood(); ll > 0 || ll < math.Log(0.001) {
+            t.Errorf("The deals scored a log-likelihood of %f.\n", ll)
+        }
+    }
+}
+
+
+/*
+ * Test that the marginals of a late hand match the share of its listed deals,
+ * with and without beliefs, and that Determinize on a full hand deals each
+ * card about as often as the marginals say.
+ */
+func TestMarginals(t *testing.T) {
+    rng.Seed(6)
+    e := Engine{ }
+
+    for i := 0; i < 4; i++ {
+        hands := GenSituation()
+        setup := Setup {
+            i,
+            (i + 2) % 4,
+            i % 2 == 0,
+            hands[4][0],
+            hands[4][0].Suit,
+            deck.Card{ },
+            -1,
+        }
+        if setup.PickedUp {
+            setup.Discard = hands[i][0]
+            hands[i][0] = setup.Top
+            hands[4][0] = setup.Discard
+        }
+
+        var state ai.TSState = NewDeterminizedState(setup, (i + 1) % 4, hands,
+                                                    []deck.Card{ }, []Trick{ })
+        start := state.(State)
+        for len(state.(State).Prior) < 3 || len(state.(State).Played) < 2 {
+            moves := e.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+        real := state.(State)
+
+        known := &Knowledge{ }
+        known.Held[(i + 1) % 4] = real.Hands[(i + 1) % 4][:1]
+        late := NewObservedState(setup, i, real.Player, real.Hands[i],
+                                 real.Played, real.Prior, known)
+        inferred := late.Copy().(State)
+        inferred.Beliefs = Infer(inferred, DefaultInference())
+
+        for _, s := range []State{ late, inferred } {
+            exact := Marginals(s)
+            listed := DealBeliefs(s.Determinizations(100000))
+            for j := range exact {
+                for holder := range exact[j] {
+                    if math.Abs(exact[j][holder] - listed[j][holder]) > 1e-9 {
+                        t.Errorf("Card %d is with %d with a probability of " +
+                                 "%f instead of %f.\n", j, holder,
+                                 exact[j][holder], listed[j][holder])
+                    }
+                }
+            }
+        }
+
+        full := NewObservedState(setup, i, start.Player, start.Hands[i],
+                                 []deck.Card{ }, []Trick{ }, nil)
+        exact := Marginals(full)
+        sampled := make([]ai.Determinization, 4000)
+        for j := range sampled {
+            d := full.Copy().(State)
+            d.Determinize()
+            sampled[j] = ai.Determinization{ d, 1 }
+        }
+        drawn := DealBeliefs(sampled)
+        for j := range exact {
+            for holder := range exact[j] {
+                if math.Abs(exact[j][holder] - drawn[j][holder]) > 0.05 {
+                    t.Errorf("Determinize put card %d with %d with a " +
+                             "probability of %f instead of %f.\n", j, holder,
+                             drawn[j][holder], exact[j][holder])
+                }
+            }
+        }
+    }
+}
+
+
+/*
+ * Sort the cards of each hand so that deals can be compared.
+ *
+ * Args:
+ *  hands: The hands of a deal.
+ *
+ * Returns:
+ *  A sorted copy of every hand.
+ */
+func sortedHands(hands [][]deck.Card) [][]deck.Card {
+    sorted := make([][]deck.Card, len(hands))
+    for i, hand := range hands {
+        sorted[i] = make([]deck.Card, len(hand))
+        copy(sorted[i], hand)
+        sort.Slice(sorted[i], func(a, b int) bool {
+            return sorted[i][a].String() < sorted[i][b].String()
+        })
+    }
+
+    return sorted
+}
+
+
+/*
+ * Test that random determinizations are uniform over every consistent deal,
+ * and follow the weights of the deals when the state has beliefs, with a
+ * chi-square test against the full list of deals on small positions.
+ */
+func TestDeterminizeUniform(t *testing.T) {
+    rng.Seed(5)
+    e := Engine{ }
+
+    for i := 0; i < 8; i++ {
+        hands := GenSituation()
+        setup := Setup {
+            3,
+            i % 4,
+            i % 2 == 0,
+            hands[4][0],
+            deck.SUITS[i % 4],
+            deck.Card{ },
+            -1,
+        }
+        if setup.PickedUp {
+            // The dealer picked up the top card and put down their last card.
+            hands[3] = append([]deck.Card{ setup.Top }, hands[3][:4]...)
+        }
+
+        var state ai.TSState = NewDeterminizedState(setup, 0, hands[:4],
+                                                    []deck.Card{ }, []Trick{ })
+        for len(state.(State).Prior) < 3 || len(state.(State).Played) < 3 {
+            moves := e.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+        real := state.(State)
+        known := NewUndeterminizedState(setup, real.Player, real.Hands[0],
+                                        real.Played, real.Prior)
+        if i >= 4 {
+            known.Beliefs = Infer(known, DefaultInference())
+        }
+
+        deals := known.Determinizations(1000)
+        if deals == nil {
+            t.Fatalf("Gave too many deals for %v.\n", known)
+        }
+
+        index := make(map[string]int)
+        for j, deal := range deals {
+            index[fmt.Sprint(sortedHands(deal.State.(State).Hands))] = j
+        }
+
+        samples := 50 * len(deals)
+        observed := make([]int, len(deals))
+        for j := 0; j < samples; j++ {
+            d := known.Copy().(State)
+            d.Determinize()
+
+            k, ok := index[fmt.Sprint(sortedHands(d.Hands))]
+            if !ok {
+                t.Fatalf("The determinization %v is not a deal.\n", d.Hands)
+            }
+            observed[k]++
+        }
+
+        chi := 0.0
+        for j, o := range observed {
+            expected := float64(samples) * deals[j].Weight
+            chi += (float64(o) - expected) * (float64(o) - expected) / expected
+        }
+
+        // The critical value for a p-value of 0.001, by the Wilson-Hilferty
+        // approximation of the chi-square distribution.
+        df := float64(len(deals) - 1)
+        c := 1 - 2 / (9 * df) + 3.09 * math.Sqrt(2 / (9 * df))
+        if critical := df * c * c * c; len(deals) > 1 && chi > critical {
+            t.Errorf("A chi-square of %f over %d deals is above %f.\n", chi,
+                     len(deals), critical)
+        }
+    }
+}
+
+
+/*
+ * Test the beliefs inferred from the bidding and first trick of a hand. Player
+ * 2 ordered up the dealer after player 1 passed, and in the first trick player
+ * 1 threw off a club rather than trump while player 0 was winning.
+ */
+func TestInfer(t *testing.T) {
+    setup := Setup {
+        3,
+        2,
+        true,
+        deck.Card { deck.S, deck.Nine },
+        deck.S,
+        deck.Card { },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.D, deck.A },
+        deck.Card { deck.D, deck.K },
+        deck.Card { deck.C, deck.A },
+        deck.Card { deck.S, deck.A },
+    }
+
+    prior := []Trick {
+        Trick {
+            []deck.Card {
+                deck.Card { deck.H, deck.A },
+                deck.Card { deck.C, deck.Ten },
+                deck.Card { deck.H, deck.K },
+                deck.Card { deck.H, deck.Q },
+            },
+            0,
+            deck.S,
+            -1,
+        },
+    }
+
+    state := NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, prior)
+    uniform := NewBeliefs(state)
+    inferred := Infer(state, DefaultInference())
+
+    for _, b := range []*Beliefs{ uniform, inferred } {
+        for _, card := range deck.CARDS {
+            total := 0.0
+            for holder := 0; holder <= OutOfPlay; holder++ {
+                total += b.Probability(card, holder)
+            }
+            if math.Abs(total - 1) > 1e-9 {
+                t.Errorf("The beliefs of %v sum to %f.\n", card, total)
+            }
+        }
+
+        certain := map[deck.Card]int {
+            deck.Card { deck.S, deck.A }: 0,
+            deck.Card { deck.S, deck.Nine }: 3,
+            deck.Card { deck.C, deck.Ten }: OutOfPlay,
+        }
+        for card, holder := range certain {
+            if b.Probability(card, holder) != 1 {
+                t.Errorf("%v is with %d with a probability of %f.\n", card,
+                         holder, b.Probability(card, holder))
+            }
+        }
+
+        if p := b.Probability(deck.Card{ deck.H, deck.J }, 1); p != 0 {
+            t.Errorf("Player 1 is out of hearts but has a heart with a " +
+                     "probability of %f.\n", p)
+        }
+    }
+
+    right := deck.Card { deck.S, deck.J }
+    if inferred.Probability(right, 1) >= uniform.Probability(right, 1) {
+        t.Errorf("Player 1 passed and threw off but has the right bower " +
+                 "with a probability of %f, up from %f.\n",
+                 inferred.Probability(right, 1), uniform.Probability(right, 1))
+    }
+
+    if inferred.Probability(right, 2) <= uniform.Probability(right, 2) {
+        t.Errorf("Player 2 called but has the right bower with a " +
+                 "probability of %f, down from %f.\n",
+                 inferred.Probability(right, 2), uniform.Probability(right, 2))
+    }
+
+    // Determinizations drawn from the beliefs should give player 1 fewer
+    // trumps and player 2 more.
+    rng.Seed(11)
+    var trumps [2][4]int
+    for i, b := range []*Beliefs{ nil, inferred } {
+        state.Beliefs = b
+        for j := 0; j < 2000; j++ {
+            d := state.Copy().(State)
+            d.Determinize()
+            for player := 1; player < 4; player++ {
+                for _, card := range d.Hands[player] {
+                    if card.IsTrump(setup.Trump) {
+                        trumps[i][player]++
+                    }
+                }
+            }
+        }
+    }
+
+    if trumps[1][1] >= trumps[0][1] || trumps[1][2] <= trumps[0][2] {
+        t.Errorf("Trumps dealt to players 1 and 2 went from %v to %v.\n",
+                 trumps[0][1:3], trumps[1][1:3])
+    }
+
+    // The beliefs change the weight of each deal but not how many there are.
+    if deals := state.Determinizations(50); deals != nil {
+        t.Errorf("Listed %d deals with beliefs when there are more than 50.\n",
+                 len(deals))
+    }
+}
@@ -0,0 +1,364 @@
+package euchre
+
+import (
+    "ai"
+    "deck"
+)
+
+
+/*
+ * List every deal of the unknown cards that is consistent with what the
+ * observer knows. Each other player gets as many cards as they still hold, no
+ * player gets a card of a suit they have shown they are out of, no exposed
+ * card is out of play, and the cards that are left over are out of play, in the
+ * kitty if the state has one. Without beliefs every such deal is as
+ * likely as any other. With beliefs, the weight of a deal is the product of
+ * the beliefs of where each card went.
+ *
+ * Args:
+ *  max: The most deals to list.
+ *
+ * Returns:
+ *  Every consistent determinization, or nil if there are more than max.
+ */
+func (s State) Determinizations(max int) []ai.Determinization {
+    base := s.Copy().(State)
+    d := newDeals(base)
+    total := d.count(0, d.needed)
+
+    // With beliefs, total is the weight of the deals rather than how many
+    // there are, so they are counted again without the beliefs.
+    n := total
+    if s.Beliefs != nil {
+        plain := s.Copy().(State)
+        plain.Beliefs = nil
+        unweighted := newDeals(plain)
+        n = unweighted.count(0, unweighted.needed)
+    }
+
+    if total == 0 || n > float64(max) {
+        return nil
+    }
+
+    deals := make([]ai.Determinization, 0, int(n))
+    owner := make([]int, len(d.cards))
+    var assign func(i int, needed [4]int, weight float64)
+    assign = func(i int, needed [4]int, weight float64) {
+        if i == len(d.cards) {
+            state := base.Copy().(State)
+            for j, player := range owner {
+                if player > 0 {
+                    seat := d.seats[player]
+                    state.Hands[seat] = append(state.Hands[seat], d.cards[j])
+                } else if len(state.Hands) > OutOfPlay {
+                    state.Hands[OutOfPlay] = append(state.Hands[OutOfPlay],
+                                                    d.cards[j])
+                }
+            }
+
+            deals = append(deals, ai.Determinization{ state, weight / total })
+            return
+        }
+
+        owners, n := d.owners(i, needed)
+        for _, player := range owners[:n] {
+            next := needed
+            if player > 0 {
+                next[player]--
+            }
+
+            if d.count(i + 1, next) > 0 {
+                owner[i] = player
+                assign(i + 1, next, weight * d.weights[i][player])
+            }
+        }
+    }
+    assign(0, d.needed, 1)
+
+    return deals
+}
+
+
+/*
+ * The exact probability of each card being with each player or out of play,
+ * found by counting the deals that are consistent with what the observer
+ * knows rather than by sampling them. This keeps to the same constraints as
+ * Determinizations, so without beliefs each probability is the share of the
+ * consistent deals that put the card there. With beliefs, each deal counts by
+ * its weight.
+ *
+ * Args:
+ *  s: The undeterminized state.
+ *
+ * Returns:
+ *  The probabilities, with the cards the observer knows the place of certain,
+ *  or nil if no deal is consistent with the state.
+ */
+func Marginals(s State) *Beliefs {
+    base := s.Copy().(State)
+    d := newDeals(base)
+    total := d.count(0, d.needed)
+    if total == 0 {
+        return nil
+    }
+
+    b := &Beliefs{ }
+    for i := range b {
+        b[i][OutOfPlay] = 1
+    }
+
+    for player, hand := range base.Hands {
+        for _, card := range hand {
+            b.set(card, player)
+        }
+    }
+
+    // reach has the total weight of the ways to deal the cards before the
+    // current one that leave each number of cards still needed. The chance of
+    // a card going to a player is then the weight of reaching the card, times
+    // its weight with that player, times the weight of dealing the rest.
+    reach := map[[4]int]float64{ d.needed: 1 }
+    for i, card := range d.cards {
+        row := &b[cardIndex(card)]
+        *row = [5]float64{ }
+
+        next := make(map[[4]int]float64)
+        for needed, weight := range reach {
+            owners, n := d.owners(i, needed)
+            for _, player := range owners[:n] {
+                after := needed
+                holder := OutOfPlay
+                if player > 0 {
+                    after[player]--
+                    holder = d.seats[player]
+                }
+
+                dealt := weight * d.weights[i][player]
+                row[holder] += dealt * d.count(i + 1, after) / total
+                next[after] += dealt
+            }
+        }
+        reach = next
+    }
+
+    return b
+}
+
+
+/*
+ * The deals of the unknown cards of a state. The players other than the
+ * observer are numbered 1 to 3, in the order they sit after the observer, and
+ * seats has the seat of each. cards are the unknown cards, and weights is the
+ * weight of each of them going out of play, at index 0, or to each of players
+ * 1 to 3, with 0 if it can not. needed is how many cards each player must be
+ * given, and the cards that are left over are out of play.
+ * counts keeps the total weight of the ways to deal the cards from some card
+ * on, with -1 for a count that has not been found yet. It is indexed by the
+ * card and the number each player still needs, with the given strides.
+ */
+type deals struct {
+    seats [4]int
+    cards []deck.Card
+    weights [][4]float64
+    needed [4]int
+    counts []float64
+    strides [4]int
+}
+
+
+/*
+ * Find the deals of the unknown cards of a state. As with unknownCards, the top
+ * card and the cards known to be held are put in their hands. A card can not
+ * go to a player who is out of its suit, an exposed card can not be out of
+ * play, and if the state has beliefs, each place a card can go is weighted by
+ * them.
+ *
+ * Args:
+ *  s: The state whose hidden cards are to be dealt.
+ *
+ * Returns:
+ *  The deals of the state.
+ */
+func newDeals(s State) *deals {
+    d := &deals{ cards: s.unknownCards() }
+    for player := 1; player < 4; player++ {
+        d.seats[player] = (s.Observer + player) % 4
+    }
+
+    var exposed []deck.Card
+    if s.Known != nil {
+        exposed = s.Known.Exposed
+    }
+
+    voids := noSuits(s.Prior, s.Setup.Trump)
+    d.weights = make([][4]float64, len(d.cards))
+    for i, card := range d.cards {
+        if !hasCard(exposed, card) {
+            d.weights[i][0] = 1
+            if s.Beliefs != nil {
+                d.weights[i][0] = s.Beliefs.Probability(card, OutOfPlay)
+            }
+        }
+
+        for player := 1; player < 4; player++ {
+            seat := d.seats[player]
+            if !hasSuit(voids[seat], card.AdjSuit(s.Setup.Trump)) {
+                d.weights[i][player] = 1
+                if s.Beliefs != nil {
+                    d.weights[i][player] = s.Beliefs.Probability(card, seat)
+                }
+            }
+        }
+    }
+
+    for player := 1; player < 4; player++ {
+        d.needed[player] = s.cardsNeeded(d.seats[player])
+    }
+    d.reset()
+
+    return d
+}
+
+
+/*
+ * Forget every count, such as when which players can hold a card changes.
+ */
+func (d *deals) reset() {
+    size := 1
+    for player := 3; player >= 0; player-- {
+        d.strides[player] = size
+        if player > 0 && d.needed[player] > 0 {
+            size *= d.needed[player] + 1
+        }
+    }
+    d.strides[0] = size
+
+    d.counts = make([]float64, size * (len(d.cards) + 1))
+    for i := range d.counts {
+        d.counts[i] = -1
+    }
+}
+
+
+/*
+ * Who a card can go to given the cards players still need. This is every
+ * player who can hold it and needs a card, and 0 for out of play if it can be
+ * and there are more cards left than are needed.
+ *
+ * Args:
+ *  i: The index of the card.
+ *  needed: The number of cards each player still needs.
+ *
+ * Returns:
+ *  The players the card can go to, in order, with 0 last if it can be out of
+ *  play, and how many of them there are.
+ */
+func (d *deals) owners(i int, needed [4]int) ([4]int, int) {
+    var owners [4]int
+    n := 0
+    spare := len(d.cards) - i
+    for player := 1; player < 4; player++ {
+        spare -= needed[player]
+        if d.weights[i][player] > 0 && needed[player] > 0 {
+            owners[n] = player
+            n++
+        }
+    }
+
+    if spare > 0 && d.weights[i][0] > 0 {
+        owners[n] = 0
+        n++
+    }
+
+    return owners, n
+}
+
+
+/*
+ * The total weight of the ways to deal the cards from the given one on, which
+ * is the number of ways if the state has no beliefs.
+ *
+ * Args:
+ *  i: The index of the first card to deal.
+ *  needed: The number of cards each player still needs.
+ *
+ * Returns:
+ *  The total weight of the consistent deals of the rest of the cards.
+ */
+func (d *deals) count(i int, needed [4]int) float64 {
+    key := i * d.strides[0]
+    for player := 1; player < 4; player++ {
+        if needed[player] < 0 || needed[player] > d.needed[player] {
+            return 0
+        }
+        key += needed[player] * d.strides[player]
+    }
+
+    if i == len(d.cards) {
+        if needed == [4]int{ } {
+            return 1
+        }
+
+        return 0
+    }
+
+    if d.counts[key] >= 0 {
+        return d.counts[key]
+    }
+
+    count := 0.0
+    owners, n := d.owners(i, needed)
+    for _, player := range owners[:n] {
+        next := needed
+        if player > 0 {
+            next[player]--
+        }
+
+        count += d.weights[i][player] * d.count(i + 1, next)
+    }
+
+    d.counts[key] = count
+    return count
+}
+
+
+/*
+ * Whether a suit is in a list of suits.
+ *
+ * Args:
+ *  suits: The list of suits.
+ *  suit: The suit to look for.
+ *
+ * Returns:
+ *  True if suit is in suits.
+ */
+func hasSuit(suits []deck.Suit, suit deck.Suit) bool {
+    for _, other := range suits {
+        if other == suit {
+            return true
+        }
+    }
+
+    return false
+}
+
+
+/*
+ * Whether a card is in a list of cards.
+ *
+ * Args:
+ *  cards: The list of cards.
+ *  card: The card to look for.
+ *
+ * Returns:
+ *  True if card is in cards.
+ */
+func hasCard(cards []deck.Card, card deck.Card) bool {
+    for _, other := range cards {
+        if other == card {
+            return true
+        }
+    }
+
+    return false
+}
@@ -3,12 +3,9 @@ package euchre
 import (
     "ai"
     "deck"
-    "math/rand"
-    "time"
+    "rng"
 )
 
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 
 /*
  * Contains all the relevant information the setup portion of a euchre game.
@@ -45,9 +42,33 @@ type Trick struct {
 
 
 
+/*
+ * What an observer knows about where cards are beyond their own hand, the
+ * cards that were played, the top card and their own discard. Held are the
+ * cards known to be in each player's hand, such as cards that were shown to
+ * the table. Kitty are the cards known to be out of play. Exposed are cards
+ * that were seen, so that they are known to be in another player's hand, but
+ * not whose.
+ */
+type Knowledge struct {
+    Held [4][]deck.Card
+    Kitty []deck.Card
+    Exposed []deck.Card
+}
+
+
 /*
  * All informative state in the euchre state tree. A state contains all
- * information prior to this moment.
+ * information prior to this moment. Hands has the hand of each player, and may
+ * have the kitty after them as Hands[OutOfPlay]. The kitty is the four cards
+ * that were not dealt to anyone, which are the top card while it is turned
+ * down, or the dealer's discard once it is picked up, and the three cards that
+ * were buried under it.
+ *
+ * An undeterminized state is what the Observer knows, which is their own hand
+ * and anything in Known. Beliefs are what the observer has inferred about
+ * where the hidden cards are, which Determinize samples from. They are nil for
+ * a determinized state, or to treat every consistent deal alike.
  */
 type State struct {
     Setup Setup
@@ -55,75 +76,142 @@ type State struct {
     Hands [][]deck.Card
     Played []deck.Card
     Prior []Trick
+    Observer int
+    Known *Knowledge `json:"-"`
+    Beliefs *Beliefs `json:"-"`
 }
 
 
-// TODO: Move state logic to another file.
-var players2And3 = map[int]bool {
-    2: true,
-    3: true,
-}
+/*
+ * Create a determinized euchre state off of an incomplete (non-terminal) euchre
+ * state. Information can range from the first move to the last move, and the
+ * incomplete information will be filled in through a determinization process.
+ *
+ * Every deal that is consistent with what the observer knows is equally likely
+ * to be drawn, or if the state has beliefs, is drawn in proportion to the
+ * product of the beliefs of where each card went. The cards are given out one
+ * at a time, and each card goes to a player, or out of play, with a chance in
+ * proportion to the total weight of the ways the rest of the cards can then be
+ * dealt. If no deal is consistent, the suits players have shown they are out
+ * of, the beliefs and the exposed cards are ignored.
+ */
+func (s State) Determinize() {
+    d := newDeals(s)
+    if d.count(0, d.needed) == 0 {
+        for i := range d.weights {
+            d.weights[i] = [4]float64{ 1, 1, 1, 1 }
+        }
+        d.reset()
+    }
 
-var players1And3 = map[int]bool {
-    1: true,
-    3: true,
-}
+    needed := d.needed
+    for i, card := range d.cards {
+        r := rng.Float64() * d.count(i, needed)
+
+        // The last owner with any deals left is kept in case rounding leaves
+        // r just above 0.
+        chosen := -1
+        var chosenNeeded [4]int
+        owners, n := d.owners(i, needed)
+        for _, player := range owners[:n] {
+            next := needed
+            if player > 0 {
+                next[player]--
+            }
 
-var players1And2 = map[int]bool {
-    1: true,
-    2: true,
-}
+            count := d.weights[i][player] * d.count(i + 1, next)
+            if count == 0 {
+                continue
+            }
 
-var playerSubsets = map[int]map[int]bool {
-    4: players1And2,
-    5: players2And3,
-    6: players1And3,
-}
+            chosen, chosenNeeded = player, next
+            if r -= count; r < 0 {
+                break
+            }
+        }
 
-var playerToSubsets = map[int][]int {
-    1: []int { 1, 4, 6 },
-    2: []int { 2, 4, 5 },
-    3: []int { 3, 5, 6 },
+        if chosen > 0 {
+            s.Hands[d.seats[chosen]] = append(s.Hands[d.seats[chosen]], card)
+        } else if chosen == 0 && len(s.Hands) > OutOfPlay {
+            s.Hands[OutOfPlay] = append(s.Hands[OutOfPlay], card)
+        }
+        if chosen >= 0 {
+            needed = chosenNeeded
+        }
+    }
 }
 
 
 /*
- * Create a determinized euchre state off of an incomplete (non-terminal) euchre
- * state. Information can range from the first move to the last move, and the
- * incomplete information will be filled in through a determinization process.
+ * Find the cards whose location is not known to the observer. The top card is
+ * put in the dealer's hand if it was picked up and has not been played yet,
+ * since that is the only place it can be, and so are the cards known to be in
+ * a player's hand that have not been played yet. If the state has a kitty, the
+ * cards known to be in it are put there.
+ *
+ * Returns:
+ *  The cards that are in the other players' hands or out of play, in deck
+ *  order.
  */
-func (s State) Determinize() {
+func (s State) unknownCards() []deck.Card {
     cardsSet := deck.NewCardsSet()
-    left := len(cardsSet)
 
     // Remove all prior cards from contention.
     for _, trick := range s.Prior {
         for _, card := range trick.Cards {
             cardsSet[card] = false
-            left--
         }
     }
 
     // Remove all played cards from contention.
     for _, card := range s.Played {
         cardsSet[card] = false
-        left--
     }
 
-    // Remove all known cards of a player's hand.
-    for _, card := range s.Hands[0] {
+    // Remove all known cards of a player's hand, and of the kitty.
+    for _, card := range s.Hands[s.Observer] {
         cardsSet[card] = false
-        left--
+    }
+
+    hasKitty := len(s.Hands) > OutOfPlay
+    if hasKitty {
+        for _, card := range s.Hands[OutOfPlay] {
+            cardsSet[card] = false
+        }
+    }
+
+    bury := func(card deck.Card) {
+        if cardsSet[card] && hasKitty {
+            s.Hands[OutOfPlay] = append(s.Hands[OutOfPlay], card)
+        }
+        cardsSet[card] = false
+    }
+
+    // Bury the cards known to be in the kitty, and give the cards known to be
+    // in a hand to that player.
+    if s.Known != nil {
+        for _, card := range s.Known.Kitty {
+            bury(card)
+        }
+
+        for player, held := range s.Known.Held {
+            for _, card := range held {
+                if cardsSet[card] && player != s.Observer {
+                    if !hasCard(s.Hands[player], card) {
+                        s.Hands[player] = append(s.Hands[player], card)
+                    }
+                    cardsSet[card] = false
+                }
+            }
+        }
     }
 
     // Remove the top card from contention if it was flipped over, or remove
     // the discarded card if you were the one who put it down.
-    if s.Setup.Dealer == 0 && s.Setup.PickedUp {
-        cardsSet[s.Setup.Discard] = false
-        left--
+    if s.Setup.Dealer == s.Observer && s.Setup.PickedUp {
+        bury(s.Setup.Discard)
     } else if !s.Setup.PickedUp {
-        cardsSet[s.Setup.Top] = false
-        left--
+        bury(s.Setup.Top)
     }
 
     // If the top card was picked up, and the top card has not already been
@@ -135,165 +223,34 @@ func (s State) Determinize() {
     if s.Setup.PickedUp && !topPlayed {
         s.Hands[s.Setup.Dealer] = append(s.Hands[s.Setup.Dealer], s.Setup.Top)
         cardsSet[s.Setup.Top] = false
-        left--
-    }
-
-    noSuits := noSuits(s.Prior, s.Setup.Trump)
-    availableCards := extractAvailableCards(cardsSet)
-
-    // Assign each card to the list of players that it can be assigned to.
-    // Further keep track of how many options each player currently has, this
-    // way we do not run out of possible cards for each player. Also, we must
-    // keep track of each subset. The meaning of the keys is as follows:
-    // 1: player {1}
-    // 2: player {2}
-    // 3: player {3}
-    // 4: players {1, 2}
-    // 5: players {2, 3}
-    // 6: players {1, 3}
-    // At no point can the number of cards needed for a subset be less than the
-    // number of options for that subset. We do not need to include the whole
-    // set as a subset, because that must naturally have more options than
-    // needed cards if we are to have a valid game.
-    subsetOptions := make(map[int]int)
-    cardToPlayers := make(map[deck.Card]map[int]bool)
-    for _, card := range availableCards {
-        for i := 1; i < 4; i++ {
-            if _, ok := noSuits[i]; ok {
-                possible := true
-                for _, suit := range noSuits[i] {
-                    if card.AdjSuit(s.Setup.Trump) == suit {
-                        possible = false
-                        break
-                    }
-
-                    if !possible {
-                        break
-                    }
-                }
-
-                if possible {
-                    if cardToPlayers[card] == nil {
-                        cardToPlayers[card] = make(map[int]bool)
-                    }
-                    cardToPlayers[card][i] = true
-                    subsetOptions[i]++
-                }
-            } else {
-                if cardToPlayers[card] == nil {
-                    cardToPlayers[card] = make(map[int]bool)
-                }
-                cardToPlayers[card][i] = true
-                subsetOptions[i]++
-            }
-        }
     }
 
-    // subsetOptions now has the singleton set counts. Now use cardToPlayers to
-    // compile the cardinality 2 subsets.
-    for idx, subset := range playerSubsets {
-        for _, players := range cardToPlayers {
-            union := intersectPlayerSets(players, subset)
-            if len(union) > 0 {
-                subsetOptions[idx]++
-            }
-        }
-    }
+    return extractAvailableCards(cardsSet)
+}
 
-    subsetHandSizes := make(map[int]int)
-    for i := 1; i < 4; i++ {
-        subsetHandSize := 5 - len(s.Prior) - len(s.Hands[i])
 
-        // If the current player has already played subtract one more from
-        // the current needed card count.
-        start := ((s.Player + 4) - len(s.Played)) % 4
-        end := start + ((s.Player + 4) - start) % 4
-        if (i >= start && i < end) || i + 4 < end {
-           subsetHandSize--
-        }
-        subsetHandSizes[i] = subsetHandSize
-    }
-    subsetHandSizes[4] = subsetHandSizes[1] + subsetHandSizes[2]
-    subsetHandSizes[5] = subsetHandSizes[2] + subsetHandSizes[3]
-    subsetHandSizes[6] = subsetHandSizes[1] + subsetHandSizes[3]
-
-    // The fact that a map is traversed randomly is needed, since otherwise the
-    // logic may be biased in what types of hands it produces.
-    notAtZero := map[int]bool {
-        1: subsetHandSizes[1] != 0,
-        2: subsetHandSizes[2] != 0,
-        3: subsetHandSizes[3] != 0,
-    }
-    numAtZero := 0
-    for _, v := range notAtZero {
-        if !v {
-            numAtZero++
-        }
+/*
+ * The number of cards a player still holds but that are not known, which is
+ * what a determinization must give them.
+ *
+ * Args:
+ *  player: The player, who is not the observer.
+ *
+ * Returns:
+ *  The number of cards the player needs.
+ */
+func (s State) cardsNeeded(player int) int {
+    needed := 5 - len(s.Prior) - len(s.Hands[player])
+
+    // If the player has already played to the current trick, they hold one
+    // card fewer.
+    start := ((s.Player + 4) - len(s.Played)) % 4
+    end := start + ((s.Player + 4) - start) % 4
+    if (player >= start && player < end) || player + 4 < end {
+       needed--
     }
 
-    for card, players := range cardToPlayers {
-        unFullPlayers := intersectPlayerSets(players, notAtZero)
-
-        if len(unFullPlayers) > 0 {
-            shuffledUnFullPlayers := shufflePlayerSetKeys(unFullPlayers)
-
-            brokenConstraints := false
-            chosen := -1
-            for _, player := range shuffledUnFullPlayers {
-                brokenConstraints = false
-
-                for _, other := range shuffledUnFullPlayers {
-                    if other != player {
-                        for _, subsetIdx := range playerToSubsets[other] {
-                            offset := 0
-                            dualSubset, dualOk := playerSubsets[subsetIdx]
-                            if dualOk {
-                                if pres, ok := dualSubset[player]; pres && ok {
-                                    offset = 1
-                                }
-                            }
-                            brokenConstraints = brokenConstraints || subsetHandSizes[subsetIdx] - offset > subsetOptions[subsetIdx] - 1
-                        }
-                    }
-                }
-
-                if !brokenConstraints {
-                    chosen = player
-                    break
-                }
-            }
-
-            // If this card can be chosen given the current constraints than
-            // assign it.
-            if chosen > 0 {
-                s.Hands[chosen] = append(s.Hands[chosen], card)
-
-                for _, subsetIdx := range playerToSubsets[chosen] {
-                    subsetHandSizes[subsetIdx]--
-                }
-
-                if subsetHandSizes[chosen] == 0 {
-                    notAtZero[chosen] = false
-                    numAtZero++
-                }
-
-                bitmap := 0
-                for player, _ := range unFullPlayers {
-                    for _, subsetIdx := range playerToSubsets[player] {
-                        subtracted := (bitmap & (1 << uint(subsetIdx))) != 0
-                        if !subtracted {
-                            subsetOptions[subsetIdx]--
-                            bitmap |= 1 << uint(subsetIdx)
-                        }
-                    }
-                }
-            }
-
-            if numAtZero == 3 {
-                break
-            }
-        }
-    }
+    return needed
 }
 
 
@@ -320,13 +277,16 @@ func (s State) Copy() ai.State {
         copyHands,
         copyPlayed,
         copyPrior,
+        s.Observer,
+        s.Known,
+        s.Beliefs,
     }
 }
 
 
 /*
  * Create a new state that only has the known information for any given instance
- * and is therefore undeterminized.
+ * and is therefore undeterminized. The observer is player 0.
  *
  * Args:
  *  setup: The setup for the game. Information such as top card, dealer, etc.
@@ -343,12 +303,38 @@ func (s State) Copy() ai.State {
  */
 func NewUndeterminizedState(setup Setup, player int, hand, played []deck.Card,
                             prior []Trick) State {
-    // Create blank values for hands other than your own.
-    hands := make([][]deck.Card, 4)
-    hands[0] = hand
-    hands[1] = make([]deck.Card, 0)
-    hands[2] = make([]deck.Card, 0)
-    hands[3] = make([]deck.Card, 0)
+    return NewObservedState(setup, 0, player, hand, played, prior, nil)
+}
+
+
+/*
+ * Create a new undeterminized state from what any player knows, such as when
+ * each seat of a simulated table determinizes from its own point of view.
+ *
+ * Args:
+ *  setup: The setup for the game, with players numbered as in the other
+ *         arguments.
+ *  observer: The player whose point of view the state is from.
+ *  player: The current player number.
+ *  hand: The current cards in the observer's hand.
+ *  played: The cards played in the current trick.
+ *  prior: The prior tricks.
+ *  known: What else the observer knows about where cards are, or nil if
+ *         nothing.
+ *
+ * Returns:
+ *  A state that has only the observer's hand filled in, and an empty kitty.
+ */
+func NewObservedState(setup Setup, observer, player int, hand,
+                      played []deck.Card, prior []Trick,
+                      known *Knowledge) State {
+    // Create blank values for hands other than the observer's own, and for the
+    // kitty.
+    hands := make([][]deck.Card, OutOfPlay + 1)
+    for i := range hands {
+        hands[i] = make([]deck.Card, 0)
+    }
+    hands[observer] = hand
 
     return State {
         setup,
@@ -356,6 +342,9 @@ func NewUndeterminizedState(setup Setup, player int, hand, played []deck.Card,
         hands,
         played,
         prior,
+        observer,
+        known,
+        nil,
     }
 }
 
@@ -370,7 +359,7 @@ func NewUndeterminizedState(setup Setup, player int, hand, played []deck.Card,
  * Args:
  *  setup: The setup for the game. Information such as top card, dealer, etc.
  *  player: The current player number.
- *  hands: A slice of each players cards.
+ *  hands: A slice of each players cards, which may be followed by the kitty.
  *  played: The slice of cards played in the current trick.
  *  prior: The prior tricks.
  *
@@ -386,6 +375,9 @@ func NewDeterminizedState(setup Setup, player int, hands [][]deck.Card,
         hands,
         played,
         prior,
+        0,
+        nil,
+        nil,
     }
 }
 
@@ -393,8 +385,14 @@ func NewDeterminizedState(setup Setup, player int, hands [][]deck.Card,
 /*
  * A TreeSearchEngine. This engine encapsulates all the game logic needed for
  * decision making in euchre in order to traverse the state tree.
+ *
+ * If MergeEquivalent is set, Successors gives only one move for each group of
+ * equivalent cards, as found by MergeEquivalent. This does not change the value
+ * of any state, but makes the trees of Minimax and MCTS much smaller.
  */
-type Engine struct { }
+type Engine struct {
+    MergeEquivalent bool
+}
 
 
 func (engine Engine) Favorable(state ai.TSState) bool {
@@ -403,6 +401,17 @@ func (engine Engine) Favorable(state ai.TSState) bool {
 }
 
 
+func (engine Engine) Mover(state ai.TSState) int {
+    cState := state.(State)
+    return cState.Player
+}
+
+
+func (engine Engine) FavorablePlayer(player int) bool {
+    return player % 2 == 0
+}
+
+
 func (engine Engine) IsTerminal(state ai.TSState) bool {
     cState := state.(State)
     return len(cState.Played) == 0 && len(cState.Prior) == 5
@@ -415,6 +424,10 @@ func (engine Engine) Successors(state ai.TSState) []ai.Move {
 
     curHand := cState.Hands[cState.Player]
     possibleIdxs := Possible(curHand, cState.Played, cState.Setup.Trump)
+    if engine.MergeEquivalent {
+        possibleIdxs = MergeEquivalent(curHand, possibleIdxs, cState.Prior,
+                                       cState.Setup.Trump)
+    }
 
     var nPlayed []deck.Card
     var nPrior []Trick
@@ -481,6 +494,39 @@ func (engine Engine) Successors(state ai.TSState) []ai.Move {
 }
 
 
+/*
+ * The card that Successors gives in place of the given card. This is the card
+ * itself unless MergeEquivalent is set and the card was merged into a higher
+ * equivalent card.
+ *
+ * Args:
+ *  state: The state the card is played from.
+ *  action: The card that is played.
+ *
+ * Returns:
+ *  The card of the successor that stands for the given card.
+ */
+func (engine Engine) Representative(state ai.TSState,
+                                    action interface{}) interface{} {
+    cState := state.(State)
+    card, ok := action.(deck.Card)
+    if !engine.MergeEquivalent || !ok {
+        return action
+    }
+
+    curHand := cState.Hands[cState.Player]
+    possibleIdxs := Possible(curHand, cState.Played, cState.Setup.Trump)
+    for _, idx := range possibleIdxs {
+        if curHand[idx] == card {
+            return EquivalentTo(card, curHand, possibleIdxs, cState.Prior,
+                                cState.Setup.Trump)
+        }
+    }
+
+    return action
+}
+
+
 func (engine Engine) Evaluation(state ai.TSState) float64 {
     cState := state.(State)
 
@@ -499,19 +545,36 @@ func (engine Engine) Evaluation(state ai.TSState) float64 {
         }
     }
 
+    return points(winCounts0, winCounts1, cState.Setup)
+}
+
+
+/*
+ * The points a hand is worth to the team of players 0 and 2, given how many
+ * tricks each team won.
+ *
+ * Args:
+ *  winCounts0: The tricks won by players 0 and 2.
+ *  winCounts1: The tricks won by players 1 and 3.
+ *  setup: The setup of the hand, for who called and who went alone.
+ *
+ * Returns:
+ *  The points the hand is worth, which are negative if the other team scores.
+ */
+func points(winCounts0, winCounts1 int, setup Setup) float64 {
     // If a player calls going alone and wins all 5 tricks then they get 4
     // points.
-    if winCounts0 == 5 && cState.Setup.AlonePlayer % 2 == 0 {
+    if winCounts0 == 5 && setup.AlonePlayer % 2 == 0 {
         return 4
-    } else if winCounts1 == 5 && cState.Setup.AlonePlayer % 2 == 1 {
+    } else if winCounts1 == 5 && setup.AlonePlayer % 2 == 1 {
         return 4
     }
 
     // If nobody who went alone won, but somebody won 5 hands or got euched then
     // that's two points.
-    if winCounts0 == 5 || (winCounts0 >= 3 && cState.Setup.Caller % 2 == 1) {
+    if winCounts0 == 5 || (winCounts0 >= 3 && setup.Caller % 2 == 1) {
         return 2
-    } else if winCounts0 == 0 || (winCounts0 < 3 && cState.Setup.Caller % 2 == 0) {
+    } else if winCounts0 == 0 || (winCounts0 < 3 && setup.Caller % 2 == 0) {
         return -2
     }
 
@@ -4,6 +4,8 @@ import (
     "ai"
     "deck"
     "fmt"
+    "math"
+    "rng"
     "testing"
 )
 
@@ -57,3 +59,990 @@ func TestRunPlayout(t *testing.T) {
     fmt.Println("Playout debug output")
     ai.RunPlayoutDebug(n, e)
 }
+
+
+/*
+ * Tests that the report MCTS gives for the root actions accounts for every
+ * playout and determinization, and that the value of the move is the mean
+ * reward of the action rather than a score with an exploration bonus.
+ */
+func TestMCTSReport(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.H, deck.Ten },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.D, deck.Q },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    played := []deck.Card {
+        deck.Card { deck.C, deck.J },
+        deck.Card { deck.C, deck.A },
+    }
+
+    var prior []Trick
+    e := Engine{ }
+    opts := ai.DefaultMCTSOptions()
+
+    // Only the queen of clubs can be played, so every playout goes through it.
+    s := NewUndeterminizedState(setup, 0, hand, played, prior)
+    report := ai.MCTSReport(s, e, 50, 4, opts)
+    actions := report.Actions
+    if len(actions) != 1 || actions[0].Visits != 200 ||
+       actions[0].Determinizations != 4 || report.Playouts != 200 {
+        t.Fatalf("Gave %v instead of a single action with 200 visits.\n",
+                 report)
+    }
+
+    a := actions[0]
+    if a.Min < -4 || a.Max > 4 || a.Mean < a.Min || a.Mean > a.Max {
+        t.Errorf("The mean %f is not in [%f, %f].\n", a.Mean, a.Min, a.Max)
+    }
+
+    s = NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, prior)
+    actions = ai.MCTSReport(s, e, 50, 4, opts).Actions
+    visits := 0
+    for _, action := range actions {
+        visits += action.Visits
+        if action.Determinizations != 4 {
+            t.Errorf("%v was seen in %d determinizations instead of 4.\n",
+                     action.Move.Action, action.Determinizations)
+        }
+    }
+    if visits != 200 {
+        t.Errorf("The root actions have %d visits instead of 200.\n", visits)
+    }
+
+    rng.Seed(1)
+    actions = ai.MCTSReport(s, e, 50, 4, opts).Actions
+    chosen := actions[ai.SelectFinal(actions, ai.MaxMean)]
+    rng.Seed(1)
+    opts.Final = ai.MaxMean
+    move, value := ai.MCTSWithOptions(s, e, 50, 4, opts)
+    if move.Action != chosen.Move.Action || value != chosen.Mean {
+        t.Errorf("Gave %v with %f instead of %v with %f.\n", move.Action,
+                 value, chosen.Move.Action, chosen.Mean)
+    }
+}
+
+
+/*
+ * Tests that a capped tree never takes up more nodes than the cap, whether it
+ * stops expanding or recycles subtrees, that a tree is only recycled when it
+ * should be, and that the search still gives a legal move.
+ */
+func TestMaxNodes(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.H, deck.Ten },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.D, deck.Q },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    var prior []Trick
+    e := Engine{ }
+    s := NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, prior)
+
+    for _, recycle := range []bool{ false, true } {
+        opts := ai.DefaultMCTSOptions()
+        opts.MaxNodes = 100
+        opts.Recycle = recycle
+
+        root := ai.SearchTree(s, e, 1000, opts)
+        if root.TreeSize() > 100 || root.GetSimulations() != 1000 ||
+           (root.Recycles() > 0) != recycle {
+            t.Errorf("With recycling %t the tree has %d nodes and %d visits " +
+                     "and was recycled %d times.\n", recycle, root.TreeSize(),
+                     root.GetSimulations(), root.Recycles())
+        }
+
+        move, _ := ai.MCTSWithOptions(s, e, 200, 2, opts)
+        legal := false
+        for _, card := range hand {
+            legal = legal || move.Action == card
+        }
+        if !legal {
+            t.Errorf("%v is not in the hand %v.\n", move.Action, hand)
+        }
+    }
+}
+
+
+/*
+ * Tests that ISMCTS keeps to the rules of the game. When following suit only
+ * one card can be played, and when leading any card in the hand can be played.
+ * Further, every iteration should be accounted for at the root.
+ */
+func TestISMCTS(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.H, deck.Ten },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.D, deck.Q },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    played := []deck.Card {
+        deck.Card { deck.C, deck.J },
+        deck.Card { deck.C, deck.A },
+    }
+
+    var prior []Trick
+    e := Engine{ }
+
+    s := NewUndeterminizedState(setup, 0, hand, played, prior)
+    move, _ := ai.ISMCTS(s, e, 200)
+    if move.Action != (deck.Card { deck.C, deck.Q }) {
+        t.Errorf("Gave %v instead of %s.\n", move.Action,
+                 deck.Card { deck.C, deck.Q })
+    }
+
+    s = NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, prior)
+    root := ai.NewISNode()
+    for i := 0; i < 200; i++ {
+        d := s.Copy()
+        d.Determinize()
+        ai.RunISPlayout(root, d, e)
+    }
+
+    if root.GetSimulations() != 200 {
+        t.Errorf("Root has %d simulations instead of 200.\n",
+                 root.GetSimulations())
+    }
+
+    move, _ = ai.ISMCTS(s, e, 200)
+    legal := false
+    for _, card := range hand {
+        legal = legal || move.Action == card
+    }
+    if !legal {
+        t.Errorf("%v is not in the hand %v.\n", move.Action, hand)
+    }
+}
+
+
+/*
+ * Tests that a forest that is kept between plays only keeps the trees whose
+ * determinizations agree with the cards that were played, that their roots are
+ * moved along those cards, and that the next search only tops up the trees to
+ * the given budget.
+ */
+func TestMCTSForest(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hands := [][]deck.Card {
+        []deck.Card {
+            deck.Card { deck.H, deck.Nine },
+            deck.Card { deck.H, deck.Ten },
+            deck.Card { deck.S, deck.A },
+            deck.Card { deck.D, deck.Q },
+            deck.Card { deck.C, deck.Q },
+        },
+        []deck.Card {
+            deck.Card { deck.D, deck.Nine },
+            deck.Card { deck.D, deck.A },
+            deck.Card { deck.C, deck.Nine },
+            deck.Card { deck.S, deck.Nine },
+            deck.Card { deck.H, deck.Q },
+        },
+        []deck.Card {
+            deck.Card { deck.D, deck.K },
+            deck.Card { deck.D, deck.Ten },
+            deck.Card { deck.C, deck.A },
+            deck.Card { deck.S, deck.Ten },
+            deck.Card { deck.H, deck.K },
+        },
+        []deck.Card {
+            deck.Card { deck.H, deck.J },
+            deck.Card { deck.C, deck.Ten },
+            deck.Card { deck.C, deck.K },
+            deck.Card { deck.S, deck.K },
+            deck.Card { deck.H, deck.A },
+        },
+    }
+
+    e := Engine{ }
+    opts := ai.DefaultMCTSOptions()
+    rng.Seed(1)
+
+    // Every player plays their first legal card until the searcher leads the
+    // fourth trick, so that few cards are unknown and some determinizations
+    // agree with the next trick.
+    real := NewDeterminizedState(setup, 0, hands, []deck.Card{ }, nil)
+    for len(real.Prior) < 3 || len(real.Played) > 0 || real.Player != 0 {
+        real = e.Successors(real)[0].State.(State)
+    }
+
+    s := NewUndeterminizedState(setup, 0, real.Hands[0], real.Played,
+                                real.Prior)
+    forest := ai.NewMCTSForest()
+    report := forest.Report(s, e, 40, 200, opts)
+    if report.Playouts != 8000 || report.Reused != 0 ||
+       len(forest.Trees()) != 200 {
+        t.Fatalf("The first search ran %d playouts, reused %d and kept %d " +
+                 "trees.\n", report.Playouts, report.Reused,
+                 len(forest.Trees()))
+    }
+
+    // Play the chosen card in the real deal, and the first legal card for
+    // every other player until it is the searcher's turn again.
+    chosen, _ := report.Best()
+    actions := []interface{}{ chosen.Action }
+    for _, move := range e.Successors(real) {
+        if move.Action == chosen.Action {
+            real = move.State.(State)
+        }
+    }
+    for real.Player != 0 && !e.IsTerminal(real) {
+        move := e.Successors(real)[0]
+        actions = append(actions, move.Action)
+        real = move.State.(State)
+    }
+
+    // A determinization agrees with the play if every card was legal in it.
+    agree := 0
+    for _, root := range forest.Trees() {
+        var state ai.TSState = root.GetState()
+        for _, action := range actions {
+            var next ai.TSState
+            for _, move := range e.Successors(state) {
+                if move.Action == action {
+                    next = move.State
+                }
+            }
+
+            state = next
+            if state == nil {
+                break
+            }
+        }
+
+        if state != nil {
+            agree++
+        }
+    }
+    if agree == 0 {
+        t.Fatalf("No determinization agrees with %v.\n", actions)
+    }
+
+    forest.Advance(e, actions)
+    if len(forest.Trees()) != agree {
+        t.Errorf("Kept %d trees instead of the %d that agree with %v.\n",
+                 len(forest.Trees()), agree, actions)
+    }
+
+    reused := 0
+    for _, root := range forest.Trees() {
+        state := root.GetState().(State)
+        if state.Player != real.Player || len(state.Prior) != len(real.Prior) {
+            t.Fatalf("A kept tree is at %v instead of %v.\n", state, real)
+        }
+
+        reused += root.GetSimulations()
+    }
+
+    s = NewUndeterminizedState(setup, 0, real.Hands[0], real.Played,
+                               real.Prior)
+    report = forest.Report(s, e, 40, 200, opts)
+    if report.Reused != reused || reused == 0 ||
+       report.Playouts + report.Reused != 8000 ||
+       len(forest.Trees()) != 200 {
+        t.Errorf("The second search ran %d playouts and reused %d instead " +
+                 "of %d, and kept %d trees.\n", report.Playouts,
+                 report.Reused, reused, len(forest.Trees()))
+    }
+}
+
+
+/*
+ * Tests that a kept ISMCTS tree follows cards that an engine that merges
+ * equivalent cards does not give as successors. Player 1 is known to hold the
+ * queen and king of clubs, so the tree only has the king, but the queen is
+ * played.
+ */
+func TestISMCTSTreeMerge(t *testing.T) {
+    setup := Setup {
+        3,
+        0,
+        false,
+        deck.Card { deck.S, deck.J },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+    hands := [][]deck.Card {
+        []deck.Card {
+            deck.Card { deck.D, deck.A },
+            deck.Card { deck.D, deck.K },
+        },
+        []deck.Card {
+            deck.Card { deck.C, deck.Q },
+            deck.Card { deck.C, deck.K },
+        },
+        []deck.Card {
+            deck.Card { deck.S, deck.Nine },
+            deck.Card { deck.S, deck.Ten },
+        },
+        []deck.Card {
+            deck.Card { deck.H, deck.Nine },
+            deck.Card { deck.H, deck.Ten },
+        },
+        []deck.Card {
+            deck.Card { deck.S, deck.J },
+            deck.Card { deck.D, deck.Nine },
+            deck.Card { deck.D, deck.Ten },
+            deck.Card { deck.C, deck.J },
+        },
+    }
+    prior := []Trick {
+        Trick {
+            []deck.Card {
+                deck.Card { deck.D, deck.J },
+                deck.Card { deck.C, deck.Nine },
+                deck.Card { deck.S, deck.Q },
+                deck.Card { deck.H, deck.Q },
+            },
+            0,
+            deck.D,
+            -1,
+        },
+        Trick {
+            []deck.Card {
+                deck.Card { deck.H, deck.J },
+                deck.Card { deck.C, deck.Ten },
+                deck.Card { deck.S, deck.K },
+                deck.Card { deck.H, deck.K },
+            },
+            0,
+            deck.D,
+            -1,
+        },
+        Trick {
+            []deck.Card {
+                deck.Card { deck.D, deck.Q },
+                deck.Card { deck.C, deck.A },
+                deck.Card { deck.S, deck.A },
+                deck.Card { deck.H, deck.A },
+            },
+            0,
+            deck.D,
+            -1,
+        },
+    }
+
+    e := Engine{ MergeEquivalent: true }
+    opts := ai.DefaultMCTSOptions()
+    rng.Seed(1)
+
+    var known Knowledge
+    known.Held[1] = hands[1]
+    s := NewObservedState(setup, 0, 0, hands[0], []deck.Card{ }, prior, &known)
+    tree := ai.NewISMCTSTree()
+    tree.Report(s, e, 500, opts)
+
+    // The ace of diamonds stands for the king, and the lower card of each
+    // other player is played.
+    real := NewDeterminizedState(setup, 0, hands, []deck.Card{ }, prior)
+    actions := []interface{} {
+        deck.Card { deck.D, deck.A },
+        deck.Card { deck.C, deck.Q },
+        deck.Card { deck.S, deck.Nine },
+        deck.Card { deck.H, deck.Nine },
+    }
+    states := make([]ai.TSState, 0, len(actions))
+    for _, action := range actions {
+        states = append(states, real)
+        for _, move := range (Engine{ }).Successors(real) {
+            if move.Action == action {
+                real = move.State.(State)
+            }
+        }
+    }
+
+    tree.Advance(e, states, actions)
+    known.Held[1] = real.Hands[1]
+    s = NewObservedState(setup, 0, 0, real.Hands[0], real.Played, real.Prior,
+                         &known)
+    report := tree.Report(s, e, 500, opts)
+    if report.Reused == 0 {
+        t.Errorf("The tree was started over after %v.\n", actions)
+    }
+}
+
+
+/*
+ * An engine that counts how many states it gives successors for.
+ */
+type countingEngine struct {
+    Engine
+    expanded *int
+}
+
+
+func (e countingEngine) Successors(state ai.TSState) []ai.Move {
+    *e.expanded++
+    return e.Engine.Successors(state)
+}
+
+
+/*
+ * Tests that merging equivalent cards does not change the minimax value of a
+ * deal after its first trick, and that it searches fewer states. Deals part of
+ * the way through a later trick are checked too.
+ */
+func TestMergeEquivalentMinimax(t *testing.T) {
+    rng.Seed(1)
+
+    for i := 0; i < 3; i++ {
+        hands := GenSituation()[:4]
+        setup := Setup {
+            i,
+            (i + 1) % 4,
+            false,
+            hands[0][0],
+            deck.SUITS[i],
+            deck.Card{ },
+            -1,
+        }
+        var state ai.TSState = NewDeterminizedState(setup, (i + 1) % 4, hands,
+                                                    []deck.Card{ }, []Trick{ })
+
+        // The first trick is played with the first legal cards to keep the
+        // full search short.
+        for len(state.(State).Prior) == 0 {
+            state = Engine{ }.Successors(state)[0].State
+        }
+
+        full, merged := 0, 0
+        value, _ := ai.Minimax(state, countingEngine{ Engine{ }, &full })
+        mergedValue, _ := ai.Minimax(state, countingEngine {
+            Engine{ MergeEquivalent: true },
+            &merged,
+        })
+
+        if value != mergedValue || merged > full {
+            t.Errorf("Merging gave %f over %d states instead of %f over %d " +
+                     "for %v.\n", mergedValue, merged, value, full, hands)
+        }
+    }
+
+    // The cards already in a trick decide who takes it, so a few cards are
+    // played at random into the third trick.
+    for i := 0; i < 4000; i++ {
+        hands := GenSituation()[:4]
+        setup := Setup {
+            i % 4,
+            (i + 1) % 4,
+            false,
+            hands[0][0],
+            deck.SUITS[i % 4],
+            deck.Card{ },
+            -1,
+        }
+        var state ai.TSState = NewDeterminizedState(setup, (i + 1) % 4, hands,
+                                                    []deck.Card{ }, []Trick{ })
+        for n := 9 + i % 3; n > 0; n-- {
+            moves := Engine{ }.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+
+        value, _ := ai.Minimax(state, Engine{ })
+        mergedValue, _ := ai.Minimax(state, Engine{ MergeEquivalent: true })
+        if value != mergedValue {
+            t.Errorf("Merging gave %f instead of %f for %v.\n", mergedValue,
+                     value, state)
+        }
+    }
+}
+
+
+/*
+ * Tests that the heuristic is the same as the evaluation once a hand is over,
+ * and stays within the points a hand can be worth before that.
+ */
+func TestHeuristic(t *testing.T) {
+    rng.Seed(1)
+    e := Engine{ }
+
+    for i := 0; i < 10; i++ {
+        hands := GenSituation()[:4]
+        setup := Setup {
+            i % 4,
+            (i + 1) % 4,
+            false,
+            hands[0][0],
+            deck.SUITS[i % 4],
+            deck.Card{ },
+            -1,
+        }
+        var state ai.TSState = NewDeterminizedState(setup, (i + 1) % 4, hands,
+                                                    []deck.Card{ }, []Trick{ })
+
+        for !e.IsTerminal(state) {
+            if h := e.Heuristic(state); h < -4 || h > 4 {
+                t.Errorf("The heuristic %f is not in [-4, 4] for %v.\n", h,
+                         state)
+            }
+
+            moves := e.Successors(state)
+            state = moves[rng.Intn(len(moves))].State
+        }
+
+        if h, v := e.Heuristic(state), e.Evaluation(state); h != v {
+            t.Errorf("The heuristic %f is not the evaluation %f at the end " +
+                     "of %v.\n", h, v, hands)
+        }
+    }
+}
+
+
+/*
+ * Tests that minimax with a cutoff past the end of the hand is the same as
+ * minimax, and that a cutoff at the root gives the heuristic.
+ */
+func TestMinimaxCutoff(t *testing.T) {
+    rng.Seed(2)
+    e := Engine{ MergeEquivalent: true }
+
+    for i := 0; i < 3; i++ {
+        hands := GenSituation()[:4]
+        setup := Setup {
+            i,
+            (i + 1) % 4,
+            false,
+            hands[0][0],
+            deck.SUITS[i],
+            deck.Card{ },
+            -1,
+        }
+        var state ai.TSState = NewDeterminizedState(setup, (i + 1) % 4, hands,
+                                                    []deck.Card{ }, []Trick{ })
+        for len(state.(State).Prior) < 2 {
+            state = e.Successors(state)[0].State
+        }
+
+        value, _ := ai.Minimax(state, e)
+        deep, _ := ai.MinimaxWithCutoff(state, e, TrickCutoff{ 5 }, nil)
+        if deep != value {
+            t.Errorf("A cutoff past the end gave %f instead of %f.\n", deep,
+                     value)
+        }
+
+        root, _ := ai.MinimaxWithCutoff(state, e, ai.DepthCutoff{ 0 }, nil)
+        if h := e.Heuristic(state); root != h {
+            t.Errorf("A cutoff at the root gave %f instead of %f.\n", root, h)
+        }
+
+        cut, move := ai.MinimaxWithCutoff(state, e, TrickCutoff{ 1 }, nil)
+        if cut < -4 || cut > 4 || move.Action == nil {
+            t.Errorf("A cutoff after a trick gave %v with %f.\n", move.Action,
+                     cut)
+        }
+    }
+}
+
+
+/*
+ * Tests that MCTS and ISMCTS still play a legal card when their playouts are
+ * cut off after a trick.
+ */
+func TestMCTSCutoff(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.H, deck.Ten },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.D, deck.Q },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    rng.Seed(1)
+    e := Engine{ }
+    opts := ai.DefaultMCTSOptions()
+    opts.Cutoff = TrickCutoff{ 1 }
+    s := NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, []Trick{ })
+
+    reports := []ai.Report {
+        ai.MCTSReport(s, e, 100, 4, opts),
+        ai.ISMCTSReport(s, e, 400, opts),
+    }
+    for _, report := range reports {
+        move, value := report.Best()
+        legal := false
+        for _, card := range hand {
+            legal = legal || card == move.Action
+        }
+
+        if !legal || value < -4 || value > 4 {
+            t.Errorf("Gave %v with %f.\n", move.Action, value)
+        }
+    }
+}
+
+
+/*
+ * Tests that MCTS and ISMCTS stop early when there is only one card to play,
+ * and that MCTS stops before its budget on some decisions, and runs past it on
+ * others, without going over its limit.
+ */
+func TestEarlyStop(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.H, deck.Ten },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.D, deck.Q },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    played := []deck.Card {
+        deck.Card { deck.C, deck.J },
+        deck.Card { deck.C, deck.A },
+    }
+
+    e := Engine{ }
+    opts := ai.DefaultMCTSOptions()
+    opts.Stop = ai.EarlyStop{ Z: 2, Interval: 20, MinVisits: 20, Extend: 2 }
+
+    // Only the queen of clubs can be played.
+    s := NewUndeterminizedState(setup, 0, hand, played, []Trick{ })
+    report := ai.MCTSReport(s, e, 500, 4, opts)
+    if !report.Separated || report.Playouts != 80 {
+        t.Errorf("A forced card took %d playouts instead of 80.\n",
+                 report.Playouts)
+    }
+    report = ai.ISMCTSReport(s, e, 2000, opts)
+    if !report.Separated || report.Playouts != 20 {
+        t.Errorf("A forced card took %d iterations instead of 20.\n",
+                 report.Playouts)
+    }
+
+    // The first lead is a real decision, which should sometimes be clear
+    // before the budget is spent and sometimes need more.
+    s = NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, []Trick{ })
+    opts.Stop = ai.EarlyStop{ Z: 3, Interval: 50, MinVisits: 100, Extend: 2 }
+    early, late := 0, 0
+    for seed := int64(1); seed <= 6; seed++ {
+        rng.Seed(seed)
+        report := ai.MCTSReport(s, e, 500, 4, opts)
+        if report.Playouts > 4000 ||
+           report.Playouts < 4000 && !report.Separated {
+            t.Errorf("The search ran %d playouts with a limit of 4000.\n",
+                     report.Playouts)
+        }
+
+        if report.Playouts < 2000 {
+            early++
+        } else if report.Playouts > 2000 {
+            late++
+        }
+    }
+
+    if early == 0 || late == 0 {
+        t.Errorf("%d searches stopped early and %d ran late instead of " +
+                 "some of each.\n", early, late)
+    }
+}
+
+
+/*
+ * Tests that MCTS and PIMC search every deal of the endgame when there are few
+ * enough, so that PIMC gives the same answer for any seed.
+ */
+func TestEnumeratedEndgame(t *testing.T) {
+    rng.Seed(4)
+    e := Engine{ }
+    hands := GenSituation()
+    setup := Setup {
+        3,
+        1,
+        false,
+        hands[4][0],
+        deck.H,
+        deck.Card{ },
+        -1,
+    }
+
+    var state ai.TSState = NewDeterminizedState(setup, 0, hands[:4],
+                                                []deck.Card{ }, []Trick{ })
+    for len(state.(State).Prior) < 3 || state.(State).Player != 0 {
+        moves := e.Successors(state)
+        state = moves[rng.Intn(len(moves))].State
+    }
+    real := state.(State)
+    s := NewUndeterminizedState(setup, 0, real.Hands[0], real.Played,
+                                real.Prior)
+
+    deals := s.Determinizations(1000)
+    if deals == nil || len(deals) > 400 {
+        t.Fatalf("Gave %d deals for the endgame.\n", len(deals))
+    }
+
+    report := ai.MCTSReport(s, e, 5, 400, ai.DefaultMCTSOptions())
+    if report.Playouts < 1800 || report.Playouts > 2200 {
+        t.Errorf("Ran %d playouts instead of about 2000.\n", report.Playouts)
+    }
+    for _, action := range report.Actions {
+        if action.Determinizations > len(deals) {
+            t.Errorf("%v was in %d trees for %d deals.\n", action.Move.Action,
+                     action.Determinizations, len(deals))
+        }
+    }
+
+    rng.Seed(1)
+    move, value := ai.PIMC(s, e, 400)
+    rng.Seed(2)
+    otherMove, otherValue := ai.PIMC(s, e, 400)
+    if move.Action != otherMove.Action || value != otherValue {
+        t.Errorf("Gave %v with %f and %v with %f for different seeds.\n",
+                 move.Action, value, otherMove.Action, otherValue)
+    }
+}
+
+
+/*
+ * Tests that a weighted search splits its playouts between determinizations by
+ * weight, leaves out those with no weight, and searches every determinization
+ * alike if none has a weight.
+ */
+func TestWeightedReport(t *testing.T) {
+    rng.Seed(6)
+    hands := GenSituation()
+    setup := Setup {
+        3,
+        1,
+        false,
+        hands[4][0],
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+    s := NewUndeterminizedState(setup, 0, hands[0], []deck.Card{ },
+                                []Trick{ })
+
+    deals := make([]ai.Determinization, 3)
+    for i, weight := range []float64{ 0.75, 0.25, 0 } {
+        d := s.Copy()
+        d.Determinize()
+        deals[i] = ai.Determinization{ d, weight }
+    }
+
+    forest := ai.NewMCTSForest()
+    report := forest.WeightedReport(deals, Engine{ }, 100,
+                                    ai.DefaultMCTSOptions())
+    trees := forest.Trees()
+    if len(trees) != 2 || report.Playouts != 300 ||
+       trees[0].GetSimulations() != 225 || trees[1].GetSimulations() != 75 {
+        t.Errorf("Ran %d playouts over %d trees instead of 225 and 75.\n",
+                 report.Playouts, len(trees))
+    }
+
+    for i := range deals {
+        deals[i].Weight = 0
+    }
+    forest.WeightedReport(deals, Engine{ }, 100, ai.DefaultMCTSOptions())
+    for i, tree := range forest.Trees() {
+        if tree.GetSimulations() != 100 {
+            t.Errorf("Tree %d of %d has %d visits instead of 100.\n", i + 1,
+                     len(forest.Trees()), tree.GetSimulations())
+        }
+    }
+    if len(forest.Trees()) != 3 {
+        t.Errorf("Searched %d trees instead of 3.\n", len(forest.Trees()))
+    }
+}
+
+
+/*
+ * Tests that PIMC keeps to the rules of the game in the same way as ISMCTS.
+ */
+func TestPIMC(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    played := []deck.Card {
+        deck.Card { deck.C, deck.J },
+        deck.Card { deck.C, deck.A },
+    }
+
+    prior := []Trick {
+        Trick {
+            []deck.Card {
+                deck.Card { deck.S, deck.Nine },
+                deck.Card { deck.S, deck.K },
+                deck.Card { deck.H, deck.Ten },
+                deck.Card { deck.S, deck.Q },
+            },
+            2,
+            deck.D,
+            -1,
+        },
+        Trick {
+            []deck.Card {
+                deck.Card { deck.D, deck.A },
+                deck.Card { deck.D, deck.Q },
+                deck.Card { deck.D, deck.K },
+                deck.Card { deck.H, deck.J },
+            },
+            3,
+            deck.D,
+            -1,
+        },
+    }
+    e := Engine{ }
+
+    s := NewUndeterminizedState(setup, 0, hand, played, prior)
+    move, _ := ai.PIMC(s, e, 10)
+    if move.Action != (deck.Card { deck.C, deck.Q }) {
+        t.Errorf("Gave %v instead of %s.\n", move.Action,
+                 deck.Card { deck.C, deck.Q })
+    }
+
+    s = NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, prior)
+    move, _ = ai.PIMC(s, e, 10)
+    legal := false
+    for _, card := range hand {
+        legal = legal || move.Action == card
+    }
+    if !legal {
+        t.Errorf("%v is not in the hand %v.\n", move.Action, hand)
+    }
+}
+
+
+/*
+ * Tests that alpha-mu with one move gives the same result as PIMC over the same
+ * worlds, and that searching more moves can only lower the value, since PIMC
+ * lets the searcher pick a different card in every world.
+ */
+func TestAlphaMu(t *testing.T) {
+    setup := Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    prior := []Trick {
+        Trick {
+            []deck.Card {
+                deck.Card { deck.S, deck.Nine },
+                deck.Card { deck.S, deck.K },
+                deck.Card { deck.H, deck.Ten },
+                deck.Card { deck.S, deck.Q },
+            },
+            2,
+            deck.D,
+            -1,
+        },
+        Trick {
+            []deck.Card {
+                deck.Card { deck.D, deck.A },
+                deck.Card { deck.D, deck.Q },
+                deck.Card { deck.D, deck.K },
+                deck.Card { deck.H, deck.J },
+            },
+            3,
+            deck.D,
+            -1,
+        },
+    }
+    e := Engine{ }
+
+    s := NewUndeterminizedState(setup, 0, hand, []deck.Card{ }, prior)
+
+    rng.Seed(3)
+    pimcMove, pimcEval := ai.PIMC(s, e, 8)
+    rng.Seed(3)
+    muMove, muEval := ai.AlphaMu(s, e, 0, 8, 1)
+    if math.Abs(pimcEval - muEval) > 1e-9 || pimcMove.Action != muMove.Action {
+        t.Errorf("Gave %v (%f) instead of the PIMC %v (%f).\n", muMove.Action,
+                 muEval, pimcMove.Action, pimcEval)
+    }
+
+    rng.Seed(3)
+    _, deepEval := ai.AlphaMu(s, e, 0, 8, 3)
+    if deepEval > muEval + 1e-9 {
+        t.Errorf("Searching 3 moves gave %f which is more than %f.\n",
+                 deepEval, muEval)
+    }
+}
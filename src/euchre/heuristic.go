@@ -0,0 +1,143 @@
+package euchre
+
+import (
+    "ai"
+    "deck"
+    "math"
+)
+
+
+/*
+ * A cutoff that stops a search once Tricks more tricks have been finished than
+ * at the root of the search.
+ */
+type TrickCutoff struct {
+    Tricks int
+}
+
+
+func (c TrickCutoff) Stop(root, state ai.TSState, depth int) bool {
+    rState := root.(State)
+    cState := state.(State)
+
+    return len(cState.Played) == 0 &&
+           len(cState.Prior) - len(rState.Prior) >= c.Tricks
+}
+
+
+/*
+ * Estimate the points a hand will be worth before it is over. The tricks that
+ * are still to be played are split between the teams in proportion to the
+ * strength of the cards each team has left, including the cards already played
+ * to the current trick. Added to the tricks each team has won, this gives the
+ * tricks each team is expected to end with, and the points for that are found
+ * as in Evaluation, going linearly between whole tricks. The state must be
+ * determinized.
+ *
+ * Args:
+ *  state: The state to score.
+ *
+ * Returns:
+ *  The expected points of the hand for the team of players 0 and 2. This is
+ *  the same as Evaluation for a terminal state.
+ */
+func (engine Engine) Heuristic(state ai.TSState) float64 {
+    cState := state.(State)
+    trump := cState.Setup.Trump
+    alone := cState.Setup.AlonePlayer
+
+    won := 0
+    for _, trick := range cState.Prior {
+        if Winner(trick.Cards, trump, trick.Led, alone) % 2 == 0 {
+            won++
+        }
+    }
+
+    left := 5 - len(cState.Prior)
+    if left == 0 {
+        return points(won, 5 - won, cState.Setup)
+    }
+
+    // The player who sits out for a partner that went alone has no cards that
+    // count.
+    sitting := -1
+    if alone >= 0 && alone < 4 {
+        sitting = (alone + 2) % 4
+    }
+
+    var strength [2]float64
+    for player, hand := range cState.Hands[:4] {
+        if player != sitting {
+            for _, card := range hand {
+                strength[player % 2] += cardStrength(card, trump)
+            }
+        }
+    }
+
+    player := Leader(cState.Played, cState.Player, alone)
+    for _, card := range cState.Played {
+        strength[player % 2] += cardStrength(card, trump)
+
+        player = (player + 1) % 4
+        if player == sitting {
+            player = (player + 1) % 4
+        }
+    }
+
+    expected := float64(won) + float64(left) / 2
+    if total := strength[0] + strength[1]; total > 0 {
+        expected = float64(won) + float64(left) * strength[0] / total
+    }
+
+    low := int(math.Floor(expected))
+    if low >= 5 {
+        return points(5, 0, cState.Setup)
+    }
+
+    frac := expected - float64(low)
+    return (1 - frac) * points(low, 5 - low, cState.Setup) +
+           frac * points(low + 1, 4 - low, cState.Setup)
+}
+
+
+/*
+ * How likely a card is to take a trick, as a rough weight. Trumps are worth the
+ * most, from the right bower down, and of the other cards only aces and kings
+ * are worth much.
+ *
+ * Args:
+ *  card: The card.
+ *  trump: The trump suit.
+ *
+ * Returns:
+ *  The weight of the card, between 0 and 1.
+ */
+func cardStrength(card deck.Card, trump deck.Suit) float64 {
+    if card.IsTrump(trump) {
+        switch {
+        case card.Value == deck.J && card.Suit == trump:
+            return 1
+        case card.Value == deck.J:
+            return 0.95
+        case card.Value == deck.A:
+            return 0.85
+        case card.Value == deck.K:
+            return 0.75
+        case card.Value == deck.Q:
+            return 0.65
+        case card.Value == deck.Ten:
+            return 0.55
+        default:
+            return 0.5
+        }
+    }
+
+    switch card.Value {
+    case deck.A:
+        return 0.45
+    case deck.K:
+        return 0.2
+    default:
+        return 0.05
+    }
+}
@@ -0,0 +1,330 @@
+package euchre
+
+import "deck"
+
+
+/*
+ * The holder in Beliefs of a card that is in no player's hand, such as a card
+ * that has been played, the top card when it was turned down, or a card left
+ * in the kitty.
+ */
+const OutOfPlay = 4
+
+
+/*
+ * What the observer believes about where every card is. Each row is a card, by
+ * its index from cardIndex, and holds the probability of each player 0 to 3
+ * having it, or of it being OutOfPlay. Each row sums to 1. A determinization
+ * weights every deal by the product of the beliefs of where each of its cards
+ * went, so rows are treated as if they were independent, and the constraint
+ * that every player holds the right number of cards is left to the deal.
+ */
+type Beliefs [24][5]float64
+
+
+/*
+ * The beliefs of the observer of a state before any inference. The cards the
+ * observer knows the place of are certain, and every other card is equally
+ * likely to be with any other player who needs cards and has not shown they
+ * are out of its suit, or to be out of play if there are more unknown cards
+ * than cards needed and it has not been exposed.
+ *
+ * Args:
+ *  s: The state to form the beliefs from.
+ *
+ * Returns:
+ *  The beliefs without any soft evidence.
+ */
+func NewBeliefs(s State) *Beliefs {
+    b := &Beliefs{ }
+    for i := range b {
+        b[i][OutOfPlay] = 1
+    }
+
+    // unknownCards puts the picked up top card in the dealer's hand, so it
+    // runs on a copy.
+    known := s.Copy().(State)
+    unknown := known.unknownCards()
+
+    for player, hand := range known.Hands {
+        for _, card := range hand {
+            b.set(card, player)
+        }
+    }
+
+    spare := len(unknown)
+    for player := 0; player < 4; player++ {
+        if player != s.Observer {
+            spare -= known.cardsNeeded(player)
+        }
+    }
+
+    var exposed []deck.Card
+    if s.Known != nil {
+        exposed = s.Known.Exposed
+    }
+
+    voids := noSuits(s.Prior, s.Setup.Trump)
+    for _, card := range unknown {
+        row := &b[cardIndex(card)]
+        row[OutOfPlay] = 0
+        if spare > 0 && !hasCard(exposed, card) {
+            row[OutOfPlay] = 1
+        }
+
+        for player := 0; player < 4; player++ {
+            if player != s.Observer && known.cardsNeeded(player) > 0 &&
+               !hasSuit(voids[player], card.AdjSuit(s.Setup.Trump)) {
+                row[player] = 1
+            }
+        }
+
+        normalize(row)
+    }
+
+    return b
+}
+
+
+/*
+ * The probability that a card is with a holder.
+ *
+ * Args:
+ *  card: The card.
+ *  holder: A player from 0 to 3, or OutOfPlay.
+ *
+ * Returns:
+ *  The probability of holder having card.
+ */
+func (b *Beliefs) Probability(card deck.Card, holder int) float64 {
+    return b[cardIndex(card)][holder]
+}
+
+
+/*
+ * Update the beliefs about a card with a piece of soft evidence about a holder.
+ * The probability of the holder having the card is multiplied by how much more
+ * likely the evidence is if they have it than if they do not, and the row of
+ * the card is normalized again. If that would leave nowhere for the card to
+ * be, the evidence is ignored.
+ *
+ * Args:
+ *  card: The card.
+ *  holder: A player from 0 to 3, or OutOfPlay.
+ *  likelihood: The likelihood ratio of the evidence, where 1 is no evidence.
+ */
+func (b *Beliefs) Observe(card deck.Card, holder int, likelihood float64) {
+    row := b[cardIndex(card)]
+    row[holder] *= likelihood
+    if normalize(&row) {
+        b[cardIndex(card)] = row
+    }
+}
+
+
+/*
+ * Make a card certain to be with a holder.
+ *
+ * Args:
+ *  card: The card.
+ *  holder: A player from 0 to 3, or OutOfPlay.
+ */
+func (b *Beliefs) set(card deck.Card, holder int) {
+    b[cardIndex(card)] = [5]float64{ }
+    b[cardIndex(card)][holder] = 1
+}
+
+
+/*
+ * Update the beliefs about every trump of a suit with the same evidence about a
+ * player.
+ *
+ * Args:
+ *  player: The player the evidence is about.
+ *  trump: The suit that is or would have been trump.
+ *  likelihood: The likelihood ratio of the evidence for each trump.
+ */
+func (b *Beliefs) observeTrumps(player int, trump deck.Suit,
+                                likelihood float64) {
+    for _, card := range deck.CARDS {
+        if card.IsTrump(trump) {
+            b.Observe(card, player, likelihood)
+        }
+    }
+}
+
+
+/*
+ * Scale a row of beliefs to sum to 1.
+ *
+ * Args:
+ *  row: The row to scale.
+ *
+ * Returns:
+ *  False, leaving the row as is, if it sums to 0.
+ */
+func normalize(row *[5]float64) bool {
+    total := 0.0
+    for _, p := range row {
+        total += p
+    }
+
+    if total <= 0 {
+        return false
+    }
+
+    for i := range row {
+        row[i] /= total
+    }
+
+    return true
+}
+
+
+/*
+ * How strongly each kind of evidence from the bidding and play moves the
+ * beliefs. Each is the likelihood ratio applied to every trump of the player
+ * who gave the evidence, so values below 1 make them less likely to hold
+ * trumps, and values above 1 make them more likely.
+ *
+ * PassTop is for a player who passed on a suit, either on the top card in the
+ * first round, or on the suit that was called in the second round. PassJack
+ * is used in place of PassTop in the first round for the dealer and their
+ * partner when the top card is a jack, since they pass up the right bower.
+ * Caller is for the player who named trump, and OrderPartner is used in its
+ * place when a player ordered the top card up to their partner. LeadTrump is
+ * for a player who led a trump. ThrowOff is for a player who could not follow
+ * suit and threw off a card that was not trump while the trick was not already
+ * their partner's.
+ */
+type Inference struct {
+    PassTop float64
+    PassJack float64
+    Caller float64
+    OrderPartner float64
+    LeadTrump float64
+    ThrowOff float64
+}
+
+
+/*
+ * The inference a SmartPlayer uses unless told otherwise.
+ *
+ * Returns:
+ *  Moderate likelihood ratios for every kind of evidence.
+ */
+func DefaultInference() Inference {
+    return Inference {
+        0.6,
+        0.4,
+        1.5,
+        2,
+        1.5,
+        0.25,
+    }
+}
+
+
+/*
+ * Infer what the observer of a state believes about where every card is from
+ * the bidding and play so far. This starts from NewBeliefs and applies each
+ * piece of evidence about the other players in turn.
+ *
+ * Args:
+ *  s: The state to infer from. Only what the observer knows is used.
+ *  inference: How strongly each kind of evidence moves the beliefs.
+ *
+ * Returns:
+ *  The beliefs of the observer.
+ */
+func Infer(s State, inference Inference) *Beliefs {
+    b := NewBeliefs(s)
+    setup := s.Setup
+
+    // The first player to bid is the one after the dealer. Everyone before the
+    // caller passed in the round trump was called in, and everyone passed in
+    // the first round if the top card was turned down.
+    passed := func(player int) bool {
+        return (player - setup.Dealer + 3) % 4 <
+               (setup.Caller - setup.Dealer + 3) % 4
+    }
+
+    for player := 0; player < 4; player++ {
+        if player == s.Observer {
+            continue
+        }
+
+        if !setup.PickedUp || passed(player) {
+            likelihood := inference.PassTop
+            if setup.Top.Value == deck.J && player % 2 == setup.Dealer % 2 {
+                likelihood = inference.PassJack
+            }
+            b.observeTrumps(player, setup.Top.Suit, likelihood)
+        }
+
+        if !setup.PickedUp && passed(player) {
+            b.observeTrumps(player, setup.Trump, inference.PassTop)
+        }
+
+        if player == setup.Caller {
+            likelihood := inference.Caller
+            if setup.PickedUp && setup.Caller == (setup.Dealer + 2) % 4 {
+                likelihood = inference.OrderPartner
+            }
+            b.observeTrumps(player, setup.Trump, likelihood)
+        }
+    }
+
+    for _, trick := range s.Prior {
+        b.observePlay(trick.Cards, trick.Led, setup.Trump, trick.Alone,
+                      s.Observer, inference)
+    }
+
+    alone := setup.AlonePlayer
+    if len(s.Played) > 0 {
+        b.observePlay(s.Played, Leader(s.Played, s.Player, alone),
+                      setup.Trump, alone, s.Observer, inference)
+    }
+
+    return b
+}
+
+
+/*
+ * Apply the evidence from the cards played to one trick.
+ *
+ * Args:
+ *  cards: The cards played to the trick so far.
+ *  led: The player who led the trick.
+ *  trump: The trump suit.
+ *  alone: The player going alone, if any.
+ *  observer: The player the beliefs are of, whose plays are not evidence.
+ *  inference: How strongly each kind of evidence moves the beliefs.
+ */
+func (b *Beliefs) observePlay(cards []deck.Card, led int, trump deck.Suit,
+                              alone, observer int, inference Inference) {
+    seats := PlayOrder(led, len(cards), alone)
+    ledSuit := cards[0].AdjSuit(trump)
+    for i, card := range cards {
+        if seats[i] == observer {
+            continue
+        }
+
+        if i == 0 {
+            if card.IsTrump(trump) {
+                b.observeTrumps(seats[i], trump, inference.LeadTrump)
+            }
+            continue
+        }
+
+        suit := card.AdjSuit(trump)
+        if ledSuit == trump || suit == ledSuit || suit == trump {
+            continue
+        }
+
+        winner := seats[WinnerIdx(cards[:i], trump)]
+        if winner != (seats[i] + 2) % 4 {
+            b.observeTrumps(seats[i], trump, inference.ThrowOff)
+        }
+    }
+}
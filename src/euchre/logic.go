@@ -93,6 +93,182 @@ func Possible(hand, played []deck.Card, trump deck.Suit) []int {
 }
 
 
+/*
+ * Of the cards that can be played, keep only one of each group of equivalent
+ * cards. Two cards of the same suit, with the left bower counted as trump, are
+ * equivalent if every card that ranks between them was played in a prior trick
+ * or is in the same hand. Whichever of them is played, the one that is kept has
+ * the same power for the rest of the hand, so searching both is wasted work.
+ * The highest card of each group is kept. A card between them in the current
+ * trick keeps them apart, since one of them may take the trick and the other
+ * not.
+ *
+ * Args:
+ *  hand: The player's current cards.
+ *  possible: The indices in hand of the cards that can be played, as given by
+ *            Possible.
+ *  prior: The prior tricks.
+ *  trump: The suit that is currently trump.
+ *
+ * Returns:
+ *  The indices in possible that are kept, in the same order.
+ */
+func MergeEquivalent(hand []deck.Card, possible []int, prior []Trick,
+                     trump deck.Suit) []int {
+    // This is called for every move of every playout, so nothing is done
+    // unless two of the cards share a suit.
+    shared := false
+    for i, a := range possible {
+        for _, b := range possible[i + 1:] {
+            shared = shared || deck.SameSuit(hand[a], hand[b], trump)
+        }
+    }
+    if !shared {
+        return possible
+    }
+
+    gone := goneCards(hand, prior)
+
+    kept := make([]int, 0, len(possible))
+    for _, i := range possible {
+        if equivalentHigher(hand[i], hand, possible, gone, trump) < 0 {
+            kept = append(kept, i)
+        }
+    }
+
+    return kept
+}
+
+
+/*
+ * Find the card that MergeEquivalent keeps in place of the given card.
+ *
+ * Args:
+ *  card: A card that can be played.
+ *  hand: The player's current cards.
+ *  possible: The indices in hand of the cards that can be played.
+ *  prior: The prior tricks.
+ *  trump: The suit that is currently trump.
+ *
+ * Returns:
+ *  The highest card in hand that is equivalent to the given card, which is the
+ *  card itself if it is kept.
+ */
+func EquivalentTo(card deck.Card, hand []deck.Card, possible []int,
+                  prior []Trick, trump deck.Suit) deck.Card {
+    gone := goneCards(hand, prior)
+    for {
+        higher := equivalentHigher(card, hand, possible, gone, trump)
+        if higher < 0 {
+            return card
+        }
+
+        card = hand[higher]
+    }
+}
+
+
+/*
+ * Find a higher card that the given card is equivalent to.
+ *
+ * Args:
+ *  card: The card to find a higher equivalent card for.
+ *  hand: The player's current cards.
+ *  possible: The indices in hand of the cards that can be played.
+ *  gone: The cards that can not be played by any other player in this trick
+ *        or a later one.
+ *  trump: The suit that is currently trump.
+ *
+ * Returns:
+ *  The index in hand of a higher equivalent card, or -1 if there is none.
+ */
+func equivalentHigher(card deck.Card, hand []deck.Card, possible []int,
+                      gone goneSet, trump deck.Suit) int {
+    for _, i := range possible {
+        higher := hand[i]
+        if higher == card || !deck.SameSuit(higher, card, trump) ||
+           !Beat(higher, card, trump) {
+            continue
+        }
+
+        // Any card between the two that another player could still play
+        // breaks the equivalence.
+        outstanding := false
+        for _, between := range deck.CARDS {
+            if between != higher && between != card &&
+               deck.SameSuit(between, card, trump) &&
+               Beat(higher, between, trump) && Beat(between, card, trump) &&
+               !gone[cardIndex(between)] {
+                outstanding = true
+                break
+            }
+        }
+
+        if !outstanding {
+            return i
+        }
+    }
+
+    return -1
+}
+
+
+/*
+ * A set of cards, indexed by cardIndex, that is small enough to not need an
+ * allocation.
+ */
+type goneSet [24]bool
+
+
+/*
+ * The cards that a player knows have no say in who takes the current trick or
+ * any later one, which are the cards in their hand and every card played in a
+ * prior trick. The cards in the current trick are not gone, since the trick is
+ * still taken by the highest of them.
+ *
+ * Args:
+ *  hand: The player's current cards.
+ *  prior: The prior tricks.
+ *
+ * Returns:
+ *  A set of the cards.
+ */
+func goneCards(hand []deck.Card, prior []Trick) goneSet {
+    var gone goneSet
+    for _, card := range hand {
+        gone[cardIndex(card)] = true
+    }
+    for _, trick := range prior {
+        for _, card := range trick.Cards {
+            gone[cardIndex(card)] = true
+        }
+    }
+
+    return gone
+}
+
+
+/*
+ * The position of a card in a goneSet.
+ *
+ * Args:
+ *  card: The card.
+ *
+ * Returns:
+ *  A number in [0, 24) that is different for every card.
+ */
+func cardIndex(card deck.Card) int {
+    suit := 0
+    for i, s := range deck.SUITS {
+        if s == card.Suit {
+            suit = i
+        }
+    }
+
+    return suit * len(deck.VALUES) + int(card.Value - deck.Nine)
+}
+
+
 /*
  * A function that returns the winning player (using the same number designation
  * as before) based on the trump suit, the cards that have been played, what the
@@ -216,6 +392,39 @@ func LeaderInclusive(played []deck.Card, player, alone int) int {
 }
 
 
+/*
+ * The players who play the cards of a trick, in order, skipping the partner of
+ * a player who went alone.
+ *
+ * Args:
+ *  led: The player who led the trick.
+ *  n: The number of cards played to the trick.
+ *  alone: The player who is going alone, if any.
+ *
+ * Returns:
+ *  The player who played each of the first n cards of the trick.
+ */
+func PlayOrder(led, n, alone int) []int {
+    sitting := -1
+    if alone >= 0 && alone < 4 {
+        sitting = (alone + 2) % 4
+    }
+
+    seats := make([]int, n)
+    player := led
+    for i := range seats {
+        seats[i] = player
+
+        player = (player + 1) % 4
+        if player == sitting {
+            player = (player + 1) % 4
+        }
+    }
+
+    return seats
+}
+
+
 /*
  * Creates a new copy of the hands in memory from the given state.
  *
@@ -247,12 +456,12 @@ func copyAllHands(state State) [][]deck.Card {
  *            true.
  *
  * Returns:
- *  A slice of the existing cards in the given set.
+ *  A slice of the existing cards in the given set, in the order of deck.CARDS.
  */
 func extractAvailableCards(cardsSet map[deck.Card]bool) []deck.Card {
     cards := make([]deck.Card, 0, len(cardsSet))
-    for card, exists := range cardsSet {
-        if exists {
+    for _, card := range deck.CARDS {
+        if exists := cardsSet[card]; exists {
             cards = append(cards, card)
         }
     }
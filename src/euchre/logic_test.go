@@ -3,6 +3,7 @@ package euchre
 import (
     "deck"
     "fmt"
+    "reflect"
     "testing"
 )
 
@@ -38,6 +39,15 @@ type possibleTest struct {
 }
 
 
+type mergeEquivalentTest struct {
+    hand []deck.Card
+    played []deck.Card
+    prior []Trick
+    trump deck.Suit
+    expected []int
+}
+
+
 type winnerTest struct {
     played []deck.Card
     trump deck.Suit
@@ -47,6 +57,13 @@ type winnerTest struct {
 }
 
 
+type relativeSeatTest struct {
+    seat int
+    viewer int
+    expected int
+}
+
+
 /*
  * Test Beat. A helper to determine which card wins in a head to head faceoff
  * where one card is chosen to be leading and there is a defined trump suit.
@@ -748,6 +765,159 @@ func TestPossible(t *testing.T) {
 }
 
 
+/*
+ * Test MergeEquivalent
+ */
+
+var mergeEquivalentTests = []mergeEquivalentTest {
+    // Cards that touch in rank are equivalent, and the higher one is kept.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.H, deck.Q },
+            deck.Card { deck.H, deck.K },
+            deck.Card { deck.S, deck.Nine },
+            deck.Card { deck.C, deck.A },
+        },
+        []deck.Card { },
+        []Trick { },
+        deck.D,
+        []int { 1, 2, 3 },
+    },
+
+    // A card that is still out between two cards keeps them apart.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.H, deck.Q },
+            deck.Card { deck.H, deck.A },
+            deck.Card { deck.S, deck.Nine },
+        },
+        []deck.Card { },
+        []Trick { },
+        deck.D,
+        []int { 0, 1, 2 },
+    },
+
+    // Once the card between them is played they are equivalent.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.H, deck.Q },
+            deck.Card { deck.H, deck.A },
+            deck.Card { deck.S, deck.Nine },
+        },
+        []deck.Card { },
+        []Trick {
+            Trick {
+                []deck.Card {
+                    deck.Card { deck.H, deck.K },
+                    deck.Card { deck.C, deck.Nine },
+                    deck.Card { deck.C, deck.Ten },
+                    deck.Card { deck.C, deck.Q },
+                },
+                1,
+                deck.D,
+                -1,
+            },
+        },
+        deck.D,
+        []int { 1, 2 },
+    },
+
+    // The jack of the left bower suit is trump, so it does not sit between
+    // the queen and ten of its printed suit.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.H, deck.Ten },
+            deck.Card { deck.H, deck.Q },
+            deck.Card { deck.D, deck.J },
+        },
+        []deck.Card { },
+        []Trick { },
+        deck.D,
+        []int { 1, 2 },
+    },
+
+    // The two bowers are next to each other in trump.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.H, deck.J },
+            deck.Card { deck.S, deck.A },
+            deck.Card { deck.D, deck.J },
+        },
+        []deck.Card { },
+        []Trick { },
+        deck.D,
+        []int { 1, 2 },
+    },
+
+    // When following suit only the cards that can be played are merged.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.D, deck.J },
+            deck.Card { deck.H, deck.J },
+            deck.Card { deck.S, deck.A },
+        },
+        []deck.Card {
+            deck.Card { deck.D, deck.Ten },
+        },
+        []Trick { },
+        deck.D,
+        []int { 0 },
+    },
+
+    // A card between two cards in the current trick keeps them apart, since
+    // the king takes the trick and the jack does not.
+    mergeEquivalentTest {
+        []deck.Card {
+            deck.Card { deck.H, deck.K },
+            deck.Card { deck.H, deck.J },
+            deck.Card { deck.C, deck.Nine },
+        },
+        []deck.Card {
+            deck.Card { deck.H, deck.Q },
+        },
+        []Trick { },
+        deck.S,
+        []int { 0, 1 },
+    },
+}
+
+
+/*
+ * Run all the above tests for MergeEquivalent on the cards that can be played.
+ */
+func TestMergeEquivalent(t *testing.T) {
+    for i, test := range mergeEquivalentTests {
+        possible := Possible(test.hand, test.played, test.trump)
+        res := MergeEquivalent(test.hand, possible, test.prior, test.trump)
+
+        if len(res) != len(test.expected) {
+            errorOut(t, test.expected, res, "mergeEquivalent", i)
+            continue
+        }
+
+        for j := 0; j < len(test.expected); j++ {
+            if test.expected[j] != res[j] {
+                errorOut(t, test.expected, res, "mergeEquivalent", i)
+            }
+        }
+
+        for _, idx := range possible {
+            rep := EquivalentTo(test.hand[idx], test.hand, possible,
+                                test.prior, test.trump)
+            kept := false
+            for _, k := range res {
+                kept = kept || test.hand[k] == rep
+            }
+
+            if !kept {
+                t.Errorf("%s stands for %s in mergeEquivalent[%d], which " +
+                         "is not kept.\n", rep, test.hand[idx], i)
+            }
+        }
+    }
+}
+
+
 /*
  * Test Winner
  */
@@ -950,6 +1120,153 @@ func TestWinner(t *testing.T) {
 }
 
 
+var relativeSeatTests = []relativeSeatTest {
+    relativeSeatTest {
+        2,
+        0,
+        2,
+    },
+    relativeSeatTest {
+        2,
+        3,
+        3,
+    },
+    relativeSeatTest {
+        0,
+        1,
+        3,
+    },
+    relativeSeatTest {
+        3,
+        2,
+        1,
+    },
+    // Not a seat, as when no one went alone.
+    relativeSeatTest {
+        -1,
+        2,
+        -1,
+    },
+}
+
+
+/*
+ * Test that seats are numbered from a viewer, and back again.
+ */
+func TestRelativeSeat(t *testing.T) {
+    for i, test := range relativeSeatTests {
+        res := RelativeSeat(test.seat, test.viewer)
+        if res != test.expected {
+            errorOut(t, test.expected, res, "TestRelativeSeat", i)
+        }
+
+        if back := AbsoluteSeat(res, test.viewer); back != test.seat {
+            errorOut(t, test.seat, back, "TestRelativeSeat", i)
+        }
+    }
+}
+
+
+/*
+ * Test that a state seen from each seat keeps that seat's hand as player 0,
+ * gives the same tricks to the same players, and turns back into the same
+ * absolute state.
+ */
+func TestPerspective(t *testing.T) {
+    setup := Setup {
+        1,
+        2,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card { deck.C, deck.Nine },
+        2,
+    }
+
+    hands := [][]deck.Card {
+        []deck.Card {
+            deck.Card { deck.S, deck.A },
+            deck.Card { deck.S, deck.K },
+            deck.Card { deck.C, deck.K },
+        },
+        []deck.Card {
+            deck.Card { deck.H, deck.A },
+            deck.Card { deck.S, deck.Q },
+            deck.Card { deck.C, deck.Ten },
+        },
+        []deck.Card {
+            deck.Card { deck.D, deck.A },
+            deck.Card { deck.D, deck.K },
+            deck.Card { deck.D, deck.Nine },
+        },
+        []deck.Card {
+            deck.Card { deck.S, deck.Nine },
+            deck.Card { deck.C, deck.Q },
+            deck.Card { deck.H, deck.Q },
+        },
+    }
+
+    prior := []Trick {
+        Trick {
+            []deck.Card {
+                deck.Card { deck.D, deck.J },
+                deck.Card { deck.H, deck.Nine },
+                deck.Card { deck.S, deck.Ten },
+            },
+            2,
+            deck.D,
+            2,
+        },
+        Trick {
+            []deck.Card {
+                deck.Card { deck.H, deck.J },
+                deck.Card { deck.C, deck.A },
+                deck.Card { deck.H, deck.K },
+            },
+            2,
+            deck.D,
+            2,
+        },
+    }
+
+    state := NewDeterminizedState(setup, 2, hands, []deck.Card{ }, prior)
+    state.Known = &Knowledge{ }
+    state.Known.Held[3] = []deck.Card{ deck.Card { deck.H, deck.Q } }
+    state.Beliefs = NewBeliefs(state)
+
+    for viewer := 0; viewer < 4; viewer++ {
+        relative := state.RelativeTo(viewer)
+        if !reflect.DeepEqual(relative.Hands[0], hands[viewer]) ||
+           relative.Player != RelativeSeat(2, viewer) ||
+           relative.Setup.Dealer != RelativeSeat(1, viewer) ||
+           relative.Known.Held[RelativeSeat(3, viewer)] == nil {
+            t.Errorf("Seen from %d the state is %v.\n", viewer, relative)
+        }
+
+        for i, trick := range relative.Prior {
+            abs := Winner(prior[i].Cards, setup.Trump, prior[i].Led,
+                          prior[i].Alone)
+            res := Winner(trick.Cards, setup.Trump, trick.Led, trick.Alone)
+            if res != RelativeSeat(abs, viewer) {
+                t.Errorf("Seen from %d trick %d was won by %d instead of " +
+                         "%d.\n", viewer, i + 1, res, RelativeSeat(abs, viewer))
+            }
+        }
+
+        card := deck.Card { deck.H, deck.Q }
+        if relative.Beliefs.Probability(card, RelativeSeat(3, viewer)) != 1 {
+            t.Errorf("Seen from %d the beliefs of %v moved.\n", viewer, card)
+        }
+
+        if back := relative.AbsoluteFrom(viewer); !reflect.DeepEqual(back,
+                                                                     state) {
+            t.Errorf("Seen from %d the state came back as %v.\n", viewer,
+                     back)
+        }
+    }
+}
+
+
 /*
  * Converts a list of suits into a set of those suits.
  *
@@ -0,0 +1,234 @@
+package euchre
+
+import "deck"
+
+
+/*
+ * The game logic numbers players relative to one of them, with that player as
+ * 0, the player to their left as 1, their partner as 2 and the player to their
+ * right as 3. A table or game log instead numbers seats the same way for every
+ * player, such as from the first dealer. The functions here move seats, setups,
+ * tricks and states between the absolute numbering and the relative numbering
+ * of any viewer, who is given by their absolute seat.
+ */
+
+
+/*
+ * The number a viewer gives to a seat.
+ *
+ * Args:
+ *  seat: The absolute seat.
+ *  viewer: The absolute seat of the viewer.
+ *
+ * Returns:
+ *  The seat relative to the viewer, or seat as it is if it is not a seat from
+ *  0 to 3, such as when no one went alone.
+ */
+func RelativeSeat(seat, viewer int) int {
+    return rotateSeat(seat, -viewer)
+}
+
+
+/*
+ * The absolute seat of the seat a viewer gives a number to.
+ *
+ * Args:
+ *  seat: The seat relative to the viewer.
+ *  viewer: The absolute seat of the viewer.
+ *
+ * Returns:
+ *  The absolute seat, or seat as it is if it is not a seat from 0 to 3.
+ */
+func AbsoluteSeat(seat, viewer int) int {
+    return rotateSeat(seat, viewer)
+}
+
+
+/*
+ * The setup as a viewer numbers its seats.
+ *
+ * Args:
+ *  viewer: The absolute seat of the viewer.
+ *
+ * Returns:
+ *  The setup with seats relative to viewer.
+ */
+func (s Setup) RelativeTo(viewer int) Setup {
+    return s.rotate(-viewer)
+}
+
+
+/*
+ * The setup with absolute seats, from one with the seats of a viewer.
+ *
+ * Args:
+ *  viewer: The absolute seat of the viewer the setup is relative to.
+ *
+ * Returns:
+ *  The setup with absolute seats.
+ */
+func (s Setup) AbsoluteFrom(viewer int) Setup {
+    return s.rotate(viewer)
+}
+
+
+/*
+ * The trick as a viewer numbers its seats.
+ *
+ * Args:
+ *  viewer: The absolute seat of the viewer.
+ *
+ * Returns:
+ *  The trick with seats relative to viewer. The cards are shared with t.
+ */
+func (t Trick) RelativeTo(viewer int) Trick {
+    return t.rotate(-viewer)
+}
+
+
+/*
+ * The trick with absolute seats, from one with the seats of a viewer.
+ *
+ * Args:
+ *  viewer: The absolute seat of the viewer the trick is relative to.
+ *
+ * Returns:
+ *  The trick with absolute seats. The cards are shared with t.
+ */
+func (t Trick) AbsoluteFrom(viewer int) Trick {
+    return t.rotate(viewer)
+}
+
+
+/*
+ * The state as a viewer numbers its seats. The hands are moved so that the
+ * viewer's hand is the hand of player 0, with the kitty left after them if
+ * there is one, and every seat in the setup, the tricks, the observer, the
+ * knowledge and the beliefs is renumbered. A state that is made for a bot from
+ * a table's absolute state should use the viewer as the observer, and only
+ * keep the viewer's own hand.
+ *
+ * Args:
+ *  viewer: The absolute seat of the viewer.
+ *
+ * Returns:
+ *  A copy of the state with seats relative to viewer.
+ */
+func (s State) RelativeTo(viewer int) State {
+    return s.rotate(-viewer)
+}
+
+
+/*
+ * The state with absolute seats, from one with the seats of a viewer.
+ *
+ * Args:
+ *  viewer: The absolute seat of the viewer the state is relative to.
+ *
+ * Returns:
+ *  A copy of the state with absolute seats.
+ */
+func (s State) AbsoluteFrom(viewer int) State {
+    return s.rotate(viewer)
+}
+
+
+/*
+ * Move a seat around the table.
+ *
+ * Args:
+ *  seat: The seat to move.
+ *  by: How many seats to move it to the left, which may be negative.
+ *
+ * Returns:
+ *  The moved seat, or seat as it is if it is not a seat from 0 to 3.
+ */
+func rotateSeat(seat, by int) int {
+    if seat < 0 || seat > 3 {
+        return seat
+    }
+
+    return ((seat + by) % 4 + 4) % 4
+}
+
+
+/*
+ * Move every seat of a setup around the table.
+ *
+ * Args:
+ *  by: How many seats to move each seat to the left.
+ *
+ * Returns:
+ *  The moved setup.
+ */
+func (s Setup) rotate(by int) Setup {
+    s.Dealer = rotateSeat(s.Dealer, by)
+    s.Caller = rotateSeat(s.Caller, by)
+    s.AlonePlayer = rotateSeat(s.AlonePlayer, by)
+
+    return s
+}
+
+
+/*
+ * Move every seat of a trick around the table.
+ *
+ * Args:
+ *  by: How many seats to move each seat to the left.
+ *
+ * Returns:
+ *  The moved trick.
+ */
+func (t Trick) rotate(by int) Trick {
+    t.Led = rotateSeat(t.Led, by)
+    t.Alone = rotateSeat(t.Alone, by)
+
+    return t
+}
+
+
+/*
+ * Move every seat of a state around the table.
+ *
+ * Args:
+ *  by: How many seats to move each seat to the left.
+ *
+ * Returns:
+ *  A moved copy of the state.
+ */
+func (s State) rotate(by int) State {
+    c := s.Copy().(State)
+    c.Setup = c.Setup.rotate(by)
+    c.Player = rotateSeat(c.Player, by)
+    c.Observer = rotateSeat(c.Observer, by)
+
+    hands := c.Hands
+    c.Hands = make([][]deck.Card, len(hands))
+    for player, hand := range hands {
+        c.Hands[rotateSeat(player, by)] = hand
+    }
+
+    for i, trick := range c.Prior {
+        c.Prior[i] = trick.rotate(by)
+    }
+
+    if s.Known != nil {
+        known := *s.Known
+        for player, held := range s.Known.Held {
+            known.Held[rotateSeat(player, by)] = held
+        }
+        c.Known = &known
+    }
+
+    if s.Beliefs != nil {
+        beliefs := *s.Beliefs
+        for i, row := range s.Beliefs {
+            for player := 0; player < 4; player++ {
+                beliefs[i][rotateSeat(player, by)] = row[player]
+            }
+        }
+        c.Beliefs = &beliefs
+    }
+
+    return c
+}
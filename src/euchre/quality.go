@@ -0,0 +1,191 @@
+package euchre
+
+import (
+    "ai"
+    "math"
+)
+
+
+/*
+ * How well beliefs about where the hidden cards are fit the real deal, summed
+ * over every card that was scored. A card is scored when its place is hidden
+ * from the observer. LogLikelihood is the total of the natural log of the
+ * probability each card was given of being where it really is, which is raised
+ * to at least Floor first so that a card given no chance of being there does
+ * not make the total infinite. Cards is the number of cards scored.
+ *
+ * For calibration, the probability of every holder other than the observer
+ * having a scored card is put in one of the equal bins from 0 to 1 by its
+ * value. Predicted is the total of the probabilities in each bin, Hits is how
+ * many of them were for the holder that really has the card, and Pairs is how
+ * many probabilities are in the bin. Beliefs are well calibrated when the
+ * Predicted and Hits of each bin are close.
+ */
+type Quality struct {
+    Floor float64
+    LogLikelihood float64
+    Cards int
+    Predicted []float64
+    Hits []float64
+    Pairs []int
+}
+
+
+/*
+ * Create an empty quality.
+ *
+ * Args:
+ *  bins: The number of bins for calibration.
+ *  floor: The least probability a card is scored with.
+ *
+ * Returns:
+ *  A quality that has not scored any cards.
+ */
+func NewQuality(bins int, floor float64) *Quality {
+    return &Quality {
+        floor,
+        0,
+        0,
+        make([]float64, bins),
+        make([]float64, bins),
+        make([]int, bins),
+    }
+}
+
+
+/*
+ * Score beliefs about the hidden cards of a state against the real deal.
+ *
+ * Args:
+ *  observed: The undeterminized state the beliefs are about.
+ *  beliefs: Where the observer believes the cards are.
+ *  real: The real deal at the same point of the hand as observed.
+ */
+func (q *Quality) Score(observed State, beliefs *Beliefs, real State) {
+    truth := holders(real)
+    hidden := observed.Copy().(State)
+    bins := len(q.Predicted)
+
+    for _, card := range hidden.unknownCards() {
+        i := cardIndex(card)
+        q.LogLikelihood += math.Log(math.Max(beliefs[i][truth[i]], q.Floor))
+        q.Cards++
+
+        for holder, p := range beliefs[i] {
+            if holder == observed.Observer {
+                continue
+            }
+
+            bin := int(p * float64(bins))
+            if bin >= bins {
+                bin = bins - 1
+            }
+
+            q.Predicted[bin] += p
+            if holder == truth[i] {
+                q.Hits[bin]++
+            }
+            q.Pairs[bin]++
+        }
+    }
+}
+
+
+/*
+ * The average log-likelihood of a scored card.
+ *
+ * Returns:
+ *  The log-likelihood per card, or 0 if no cards were scored.
+ */
+func (q *Quality) MeanLogLikelihood() float64 {
+    if q.Cards == 0 {
+        return 0
+    }
+
+    return q.LogLikelihood / float64(q.Cards)
+}
+
+
+/*
+ * The expected calibration error, which is how far the average probability in
+ * each bin is from how often those holders had the card, averaged over the
+ * bins by how many probabilities are in each.
+ *
+ * Returns:
+ *  The calibration error from 0 to 1, or 0 if no cards were scored.
+ */
+func (q *Quality) CalibrationError() float64 {
+    pairs := 0
+    diff := 0.0
+    for bin := range q.Predicted {
+        pairs += q.Pairs[bin]
+        diff += math.Abs(q.Predicted[bin] - q.Hits[bin])
+    }
+
+    if pairs == 0 {
+        return 0
+    }
+
+    return diff / float64(pairs)
+}
+
+
+/*
+ * The beliefs that a set of determinizations gives about where every card is.
+ * The probability of a holder having a card is the share of the weight of the
+ * determinizations in which they have it.
+ *
+ * Args:
+ *  deals: The determinizations. If their weights sum to 0, each is given the
+ *         same weight.
+ *
+ * Returns:
+ *  The beliefs of the determinizations.
+ */
+func DealBeliefs(deals []ai.Determinization) *Beliefs {
+    b := &Beliefs{ }
+
+    total := 0.0
+    for _, deal := range deals {
+        total += deal.Weight
+    }
+
+    for _, deal := range deals {
+        weight := 1 / float64(len(deals))
+        if total > 0 {
+            weight = deal.Weight / total
+        }
+
+        for i, holder := range holders(deal.State.(State)) {
+            b[i][holder] += weight
+        }
+    }
+
+    return b
+}
+
+
+/*
+ * Where every card is in a determinized state.
+ *
+ * Args:
+ *  s: The determinized state.
+ *
+ * Returns:
+ *  The player who has each card, by the index of the card, or OutOfPlay if no
+ *  player has it.
+ */
+func holders(s State) [24]int {
+    var where [24]int
+    for i := range where {
+        where[i] = OutOfPlay
+    }
+
+    for player, hand := range s.Hands[:4] {
+        for _, card := range hand {
+            where[cardIndex(card)] = player
+        }
+    }
+
+    return where
+}
@@ -0,0 +1,62 @@
+package player
+
+import (
+    "ai"
+    "euchre"
+)
+
+
+/*
+ * A player that uses alpha-mu search. Like the PIMCPlayer it solves sampled
+ * determinizations exactly, but for its next few moves it has to pick the same
+ * card in every determinization, which avoids the strategy fusion of PIMC for
+ * those moves. The number of its own moves it searches this way is maxMoves,
+ * and a maxMoves of 1 plays the same as the PIMCPlayer.
+ */
+type AlphaMuPlayer struct {
+    solvingPlayer
+}
+
+
+/*
+ * Creates a new AlphaMuPlayer with the given attributes. Confidence is on the
+ * same scale as for the SmartPlayer, which is the range of the evaluation
+ * function.
+ *
+ * Args:
+ *  pickupConfidence: The confidence needed to tell the dealer to pickup.
+ *  callConfidence: The confidence needed to call suit after everybody passes.
+ *  aloneConfidence: The confidence needed to go alone.
+ *  maxMoves: The number of the player's own moves to search over all the
+ *            determinizations at once.
+ *  pickupDeterminizations: The amount of determinizations for picking up.
+ *  callDeterminizations: The amount of determinizations for calling suit.
+ *  playDeterminizations: The amount of determinizations for a general play.
+ *  aloneDeterminizations: The amount of determinizations for going alone.
+ *
+ * Returns:
+ *  An AlphaMuPlayer that uses the given parameters in its decision making.
+ */
+func NewAlphaMu(pickupConfidence float64, callConfidence float64,
+                aloneConfidence float64, maxMoves int,
+                pickupDeterminizations int, callDeterminizations int,
+                playDeterminizations int,
+                aloneDeterminizations int) (*AlphaMuPlayer) {
+    solve := func(s euchre.State, searcher int,
+                  deters int) (ai.Move, float64) {
+        return ai.AlphaMu(s, euchre.Engine{ }, searcher, deters, maxMoves)
+    }
+
+    return &AlphaMuPlayer{
+        solvingPlayer{
+            pickupConfidence,
+            callConfidence,
+            aloneConfidence,
+            pickupDeterminizations,
+            callDeterminizations,
+            playDeterminizations,
+            aloneDeterminizations,
+            solve,
+        },
+    }
+}
@@ -0,0 +1,214 @@
+package player
+
+import (
+    "ai"
+    "deck"
+    "euchre"
+    "math"
+)
+
+
+/*
+ * A player that uses Perfect Information Monte Carlo search. For each decision
+ * it samples some determinizations of the unknown cards, solves each one
+ * exactly with minimax, and picks the card or decision with the best average
+ * evaluation. It has the same confidence knobs as the SmartPlayer, but only
+ * needs a number of determinizations since each one is solved exactly.
+ */
+type PIMCPlayer struct {
+    solvingPlayer
+}
+
+
+/*
+ * A search that solves determinizations of a state exactly, such as ai.PIMC.
+ *
+ * Args:
+ *  s: The undeterminized state to search from.
+ *  searcher: The player that the search is for.
+ *  deters: The number of determinizations to solve.
+ *
+ * Returns:
+ *  The best move for the player to move and its expected evaluation.
+ */
+type solver func(s euchre.State, searcher int, deters int) (ai.Move, float64)
+
+
+/*
+ * The bidding and play of a player that makes every decision with a solver.
+ * The players that solve determinizations exactly only differ in the solver.
+ */
+type solvingPlayer struct {
+    pickupConfidence float64
+    callConfidence float64
+    aloneConfidence float64
+
+    pickupDeterminizations int
+    callDeterminizations int
+    playDeterminizations int
+    aloneDeterminizations int
+
+    solve solver
+}
+
+
+/*
+ * Creates a new PIMCPlayer with the given attributes. Confidence is on the same
+ * scale as for the SmartPlayer, which is the range of the evaluation function.
+ *
+ * Args:
+ *  pickupConfidence: The confidence needed to tell the dealer to pickup.
+ *  callConfidence: The confidence needed to call suit after everybody passes.
+ *  aloneConfidence: The confidence needed to go alone.
+ *  pickupDeterminizations: The amount of determinizations for picking up.
+ *  callDeterminizations: The amount of determinizations for calling suit.
+ *  playDeterminizations: The amount of determinizations for a general play.
+ *  aloneDeterminizations: The amount of determinizations for going alone.
+ *
+ * Returns:
+ *  A PIMCPlayer that uses the given parameters in its decision making.
+ */
+func NewPIMC(pickupConfidence float64, callConfidence float64,
+             aloneConfidence float64, pickupDeterminizations int,
+             callDeterminizations int, playDeterminizations int,
+             aloneDeterminizations int) (*PIMCPlayer) {
+    solve := func(s euchre.State, searcher int,
+                  deters int) (ai.Move, float64) {
+        return ai.PIMC(s, euchre.Engine{ }, deters)
+    }
+
+    return &PIMCPlayer{
+        solvingPlayer{
+            pickupConfidence,
+            callConfidence,
+            aloneConfidence,
+            pickupDeterminizations,
+            callDeterminizations,
+            playDeterminizations,
+            aloneDeterminizations,
+            solve,
+        },
+    }
+}
+
+
+func (p *solvingPlayer) Pickup(hand []deck.Card, top deck.Card, who int) bool {
+    var discard deck.Card
+
+    actualHand := make([]deck.Card, len(hand))
+    copy(actualHand, hand)
+    if who == 0 {
+        actualHand, discard = p.Discard(actualHand, top)
+    }
+
+    setup := euchre.Setup {
+        Dealer: who,
+        Caller: 0,
+        PickedUp: true,
+        Top: top,
+        Trump: top.Suit,
+        Discard: discard,
+        AlonePlayer: -1,
+    }
+
+    nPlayer := (who + 1) % 4
+    s := euchre.NewUndeterminizedState(setup, nPlayer, actualHand,
+                                       make([]deck.Card, 0),
+                                       make([]euchre.Trick, 0))
+    _, expected := p.solve(s, 0, p.pickupDeterminizations)
+
+    return (nPlayer % 2 == 0 && expected > p.pickupConfidence) ||
+           (nPlayer % 2 == 1 && expected < -1 * p.pickupConfidence)
+}
+
+
+/*
+ * A player that solves determinizations discards the same way as the
+ * RulePlayer, since the discard is not searched.
+ */
+func (p *solvingPlayer) Discard(hand []deck.Card,
+                                top deck.Card) ([]deck.Card, deck.Card) {
+    rule := RulePlayer{ }
+    return rule.Discard(hand, top)
+}
+
+
+func (p *solvingPlayer) Call(hand []deck.Card, top deck.Card,
+                             who int) (deck.Suit, bool) {
+    max := math.Inf(-1)
+    var maxSuit deck.Suit
+
+    for _, suit := range deck.SUITS {
+        if suit == top.Suit {
+            continue
+        }
+
+        setup := euchre.Setup {
+            Dealer: who,
+            Caller: 0,
+            PickedUp: false,
+            Top: top,
+            Trump: suit,
+            AlonePlayer: -1,
+        }
+
+        s := euchre.NewUndeterminizedState(setup, 0, hand,
+                                           make([]deck.Card, 0),
+                                           make([]euchre.Trick, 0))
+        _, expected := p.solve(s, 0, p.callDeterminizations)
+
+        if expected > max {
+            max = expected
+            maxSuit = suit
+        }
+    }
+
+    return maxSuit, max > p.callConfidence
+}
+
+
+func (p *solvingPlayer) Alone(hand []deck.Card, top deck.Card, who int) bool {
+    nPlayer := (who + 1) % 4
+
+    nHand := make([]deck.Card, len(hand))
+    copy(nHand, hand)
+    _, discard := p.Discard(nHand, top)
+
+    setup := euchre.Setup {
+        Dealer: who,
+        Caller: 0,
+        PickedUp: false,
+        Top: top,
+        Trump: top.Suit,
+        Discard: discard,
+        AlonePlayer: 0,
+    }
+
+    s := euchre.NewUndeterminizedState(setup, nPlayer, hand,
+                                       make([]deck.Card, 0),
+                                       make([]euchre.Trick, 0))
+    _, expected := p.solve(s, 0, p.aloneDeterminizations)
+
+    return (nPlayer % 2 == 0 && expected > p.aloneConfidence) ||
+           (nPlayer % 2 == 1 && expected < -1 * p.aloneConfidence)
+}
+
+
+func (p *solvingPlayer) Play(player int, setup euchre.Setup, hand,
+                             played []deck.Card,
+                             prior []euchre.Trick) ([]deck.Card, deck.Card) {
+    s := euchre.NewObservedState(setup, player, player, hand, played, prior,
+                                 nil)
+    chosenMove, _ := p.solve(s, player, p.playDeterminizations)
+
+    card := chosenMove.Action.(deck.Card)
+
+    nHand := make([]deck.Card, 0)
+    for i := 0; i < len(hand); i++ {
+        if card != hand[i] {
+            nHand = append(nHand, hand[i])
+        }
+    }
+
+    return nHand, card
+}
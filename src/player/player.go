@@ -1,6 +1,7 @@
 package player
 
 import (
+    "ai"
     "deck"
     "euchre"
 )
@@ -104,3 +105,21 @@ type Player interface {
     Play(player int, setup euchre.Setup, hand []deck.Card, played []deck.Card,
          prior []euchre.Trick) ([]deck.Card, deck.Card)
 }
+
+
+/*
+ * An optional interface for players whose decisions come from a tree search,
+ * so that analysis tools can see why a decision was made. Callers should check
+ * for it with a type assertion, since most players do not search.
+ */
+type Analyst interface {
+    /*
+     * Gives the reports of the searches behind the player's last decision that
+     * needed a search. Pickup, Alone and Play run one search, while Call runs
+     * one for each suit it considers, in the order of deck.SUITS.
+     *
+     * Returns:
+     *  The reports of the last decision, or nil if no decision has been made.
+     */
+    LastReports() []ai.Report
+}
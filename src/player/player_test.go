@@ -1,7 +1,11 @@
 package player
 
 import (
+    "ai"
     "deck"
+    "euchre"
+    "math"
+    "rng"
     "testing"
 )
 
@@ -171,6 +175,207 @@ func TestDiscard(t *testing.T) {
 }
 
 
+/*
+ * Tests that a SmartPlayer gives the reports of the searches behind its last
+ * decision, and that they agree with the decision that was made.
+ */
+func TestLastReports(t *testing.T) {
+    smart := NewSmart(PICKUP_CONF, CALL_CONF, ALONE_CONF, 10, 2, 10, 2, 10, 2,
+                      10, 2)
+    var analyst Analyst = smart
+    if analyst.LastReports() != nil {
+        t.Errorf("Gave reports before any decision.\n")
+    }
+
+    setup := euchre.Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+    hand := []deck.Card {
+        deck.Card { deck.H, deck.Nine },
+        deck.Card { deck.H, deck.Ten },
+        deck.Card { deck.S, deck.A },
+        deck.Card { deck.D, deck.Q },
+        deck.Card { deck.C, deck.Q },
+    }
+
+    _, card := smart.Play(0, setup, hand, []deck.Card{ }, []euchre.Trick{ })
+    reports := analyst.LastReports()
+    if len(reports) != 1 {
+        t.Fatalf("Gave %d reports for a play instead of 1.\n", len(reports))
+    }
+
+    chosen, _ := reports[0].Best()
+    if chosen.Action != card || reports[0].Playouts != 20 {
+        t.Errorf("The report chose %v in %d playouts instead of %s in 20.\n",
+                 chosen.Action, reports[0].Playouts, card)
+    }
+
+    smart.Call(hand, deck.Card { deck.D, deck.Nine }, 1)
+    if len(analyst.LastReports()) != 3 {
+        t.Errorf("Gave %d reports for a call instead of 3.\n",
+                 len(analyst.LastReports()))
+    }
+}
+
+
+/*
+ * Tests that a SmartPlayer in a seat other than 0 searches from its own hand,
+ * so that the card it plays is always one it has.
+ */
+func TestPlayFromSeat(t *testing.T) {
+    smart := NewSmart(PICKUP_CONF, CALL_CONF, ALONE_CONF, 10, 2, 10, 2, 10, 2,
+                      10, 2)
+    rng.Seed(2)
+
+    for _, search := range []Search{ DeterminizedSearch,
+                                     InformationSetSearch } {
+        smart.SetSearch(search)
+        for i := 0; i < 20; i++ {
+            hands := euchre.GenSituation()
+            // With player 1 dealing, player 2 leads the first trick. The top
+            // card was turned down, so trump is another suit.
+            setup := euchre.Setup {
+                1,
+                1,
+                false,
+                hands[4][0],
+                hands[4][0].Suit.Left(),
+                deck.Card{ },
+                -1,
+            }
+
+            hand := append([]deck.Card{ }, hands[2]...)
+            _, card := smart.Play(2, setup, hand, []deck.Card{ },
+                                  []euchre.Trick{ })
+            found := false
+            for _, c := range hands[2] {
+                found = found || c == card
+            }
+            if !found {
+                t.Errorf("Player 2 played %s from %v.\n", card, hands[2])
+            }
+        }
+    }
+}
+
+
+/*
+ * Tests that a SmartPlayer that keeps its search carries the visits of one play
+ * over to the next play of the same hand, and starts over for another hand.
+ */
+func TestReuse(t *testing.T) {
+    smart := NewSmart(PICKUP_CONF, CALL_CONF, ALONE_CONF, 10, 2, 10, 2, 400, 5,
+                      10, 2)
+    smart.SetSearch(InformationSetSearch)
+    smart.SetReuse(true)
+    rng.Seed(1)
+
+    setup := euchre.Setup {
+        1,
+        1,
+        true,
+        deck.Card { deck.D, deck.Nine },
+        deck.D,
+        deck.Card{ },
+        -1,
+    }
+    prior := []euchre.Trick {
+        euchre.Trick {
+            []deck.Card {
+                deck.Card { deck.H, deck.Nine },
+                deck.Card { deck.H, deck.Q },
+                deck.Card { deck.H, deck.K },
+                deck.Card { deck.H, deck.A },
+            },
+            0,
+            deck.D,
+            -1,
+        },
+        euchre.Trick {
+            []deck.Card {
+                deck.Card { deck.H, deck.J },
+                deck.Card { deck.D, deck.Q },
+                deck.Card { deck.D, deck.Nine },
+                deck.Card { deck.D, deck.K },
+            },
+            3,
+            deck.D,
+            -1,
+        },
+        euchre.Trick {
+            []deck.Card {
+                deck.Card { deck.S, deck.K },
+                deck.Card { deck.S, deck.A },
+                deck.Card { deck.S, deck.Nine },
+                deck.Card { deck.S, deck.Ten },
+            },
+            3,
+            deck.D,
+            -1,
+        },
+    }
+    // Either card the smart player has wins the fourth trick, so it leads the
+    // last trick and its next play is four cards after this one.
+    hands := [][]deck.Card {
+        []deck.Card {
+            deck.Card { deck.D, deck.J },
+            deck.Card { deck.D, deck.A },
+        },
+        []deck.Card {
+            deck.Card { deck.C, deck.Nine },
+            deck.Card { deck.H, deck.Ten },
+        },
+        []deck.Card {
+            deck.Card { deck.D, deck.Ten },
+            deck.Card { deck.C, deck.A },
+        },
+        []deck.Card {
+            deck.Card { deck.C, deck.Ten },
+            deck.Card { deck.C, deck.K },
+        },
+    }
+
+    hand, card := smart.Play(0, setup, hands[0], []deck.Card{ }, prior)
+    if smart.LastReports()[0].Reused != 0 {
+        t.Errorf("The first play of a hand reused %d visits.\n",
+                 smart.LastReports()[0].Reused)
+    }
+
+    // The other players play their first legal card until it is the smart
+    // player's turn again.
+    e := euchre.Engine{ }
+    real := euchre.NewDeterminizedState(setup, 0, hands, []deck.Card{ },
+                                        prior)
+    for _, move := range e.Successors(real) {
+        if move.Action == card {
+            real = move.State.(euchre.State)
+        }
+    }
+    for real.Player != 0 {
+        real = e.Successors(real)[0].State.(euchre.State)
+    }
+
+    smart.Play(0, setup, hand, real.Played, real.Prior)
+    report := smart.LastReports()[0]
+    if report.Reused == 0 || report.Reused + report.Playouts != 2000 {
+        t.Errorf("The second play ran %d playouts and reused %d.\n",
+                 report.Playouts, report.Reused)
+    }
+
+    smart.Play(0, setup, hands[0], []deck.Card{ }, prior)
+    if smart.LastReports()[0].Reused != 0 {
+        t.Errorf("A new hand reused %d visits.\n",
+                 smart.LastReports()[0].Reused)
+    }
+}
+
+
 /*
  * Returns a list of all the different Player implementations to test.
  *
@@ -190,3 +395,205 @@ func getTestablePlayers() []Player {
 
     return players
 }
+
+
+/*
+ * Tests that deals are weighted by how well the rule player explains the cards
+ * the other players played. Every player plays as the rule player for a trick
+ * and a half, so the true deal can be explained, while some random deals can
+ * not.
+ */
+func TestPolicyWeighting(t *testing.T) {
+    rng.Seed(4)
+    rule := NewRule("")
+    e := euchre.Engine{ }
+
+    hands := euchre.GenSituation()
+    setup := euchre.Setup {
+        3,
+        1,
+        false,
+        hands[4][0],
+        deck.S,
+        deck.Card{ },
+        -1,
+    }
+
+    var state ai.TSState = euchre.NewDeterminizedState(setup, 0, hands[:4],
+                                                       []deck.Card{ },
+                                                       []euchre.Trick{ })
+    for i := 0; i < 6; i++ {
+        s := state.(euchre.State)
+        hand := make([]deck.Card, len(s.Hands[s.Player]))
+        copy(hand, s.Hands[s.Player])
+
+        _, card := rule.Play(s.Player, s.Setup, hand, s.Played, s.Prior)
+        for _, move := range e.Successors(state) {
+            if move.Action == card {
+                state = move.State
+            }
+        }
+    }
+    real := state.(euchre.State)
+    known := euchre.NewUndeterminizedState(setup, real.Player, real.Hands[0],
+                                           real.Played, real.Prior)
+
+    strict := PolicyWeighting{ Model: rule, Samples: 50 }
+    if l := strict.Likelihood(real); l == 0 {
+        t.Errorf("The true deal has a likelihood of 0.\n")
+    }
+
+    // Player 1 followed the king of hearts with the nine. With no other heart
+    // that is the only card they could play, but the rule player would take
+    // the trick with the ace of hearts if they had it.
+    forced := euchre.NewDeterminizedState(
+        euchre.Setup {
+            3,
+            1,
+            false,
+            deck.Card { deck.C, deck.Nine },
+            deck.S,
+            deck.Card{ },
+            -1,
+        },
+        0,
+        [][]deck.Card {
+            []deck.Card {
+                deck.Card { deck.D, deck.A },
+                deck.Card { deck.D, deck.K },
+                deck.Card { deck.C, deck.A },
+                deck.Card { deck.S, deck.A },
+            },
+            []deck.Card {
+                deck.Card { deck.D, deck.Nine },
+                deck.Card { deck.D, deck.Ten },
+                deck.Card { deck.C, deck.K },
+                deck.Card { deck.C, deck.Q },
+            },
+            []deck.Card {
+                deck.Card { deck.S, deck.K },
+                deck.Card { deck.S, deck.Q },
+                deck.Card { deck.D, deck.Q },
+                deck.Card { deck.D, deck.J },
+            },
+            []deck.Card {
+                deck.Card { deck.S, deck.Ten },
+                deck.Card { deck.S, deck.Nine },
+                deck.Card { deck.C, deck.J },
+                deck.Card { deck.C, deck.Ten },
+            },
+        },
+        []deck.Card{ },
+        []euchre.Trick {
+            euchre.Trick {
+                []deck.Card {
+                    deck.Card { deck.H, deck.K },
+                    deck.Card { deck.H, deck.Nine },
+                    deck.Card { deck.H, deck.Q },
+                    deck.Card { deck.H, deck.J },
+                },
+                0,
+                deck.S,
+                -1,
+            },
+        })
+    unforced := forced.Copy().(euchre.State)
+    unforced.Hands[1][0] = deck.Card { deck.H, deck.A }
+
+    loose := PolicyWeighting{ Model: rule, Samples: 5, Epsilon: 0.1 }
+    likelihoods := []float64 {
+        strict.Likelihood(forced),
+        strict.Likelihood(unforced),
+        loose.Likelihood(forced),
+        loose.Likelihood(unforced),
+    }
+    expected := []float64{ 1, 0, 1, 0.05 }
+    for i := range likelihoods {
+        if math.Abs(likelihoods[i] - expected[i]) > 1e-9 {
+            t.Errorf("Gave likelihoods of %v instead of %v.\n", likelihoods,
+                     expected)
+            break
+        }
+    }
+
+    for _, w := range []PolicyWeighting{ strict, loose } {
+        for _, reject := range []bool{ false, true } {
+            w.Reject = reject
+            deals := w.Determinizations(known, 20)
+
+            total := 0.0
+            for _, deal := range deals {
+                total += deal.Weight
+                l := w.Likelihood(deal.State.(euchre.State))
+                if deal.Weight > 0 && l == 0 {
+                    t.Errorf("A deal the model can not explain has a weight " +
+                             "of %f.\n", deal.Weight)
+                }
+                equal := 1 / float64(len(deals))
+                if reject && w.Epsilon == 0 && deal.Weight != equal {
+                    t.Errorf("A kept deal has a weight of %f out of %d.\n",
+                             deal.Weight, len(deals))
+                }
+            }
+
+            if len(deals) == 0 || math.Abs(total - 1) > 1e-9 {
+                t.Errorf("Gave %d deals with a total weight of %f.\n",
+                         len(deals), total)
+            }
+        }
+    }
+
+    // A model that only agrees with the rule player one time in ten makes
+    // every deal unlikely, but the deals where player 1 does not have the ace
+    // of hearts should still be kept over the ones where they do.
+    rare := PolicyWeighting{ Model: &rarePlayer{ rule, 10, 0 }, Samples: 10,
+                             Reject: true }
+    seen := euchre.NewUndeterminizedState(forced.Setup, forced.Player,
+                                          forced.Hands[0], forced.Played,
+                                          forced.Prior)
+    deals := rare.Determinizations(seen, 20)
+    if len(deals) != 20 {
+        t.Errorf("Kept %d of 20 unlikely deals.\n", len(deals))
+    }
+    for _, deal := range deals {
+        if strict.Likelihood(deal.State.(euchre.State)) == 0 {
+            t.Errorf("Kept a deal the model can not explain.\n")
+            break
+        }
+    }
+
+    // A model that never agrees keeps no deal, so the deals are not weighted,
+    // and at least one deal is always given.
+    never := PolicyWeighting{ Model: &rarePlayer{ rule, 0, 0 }, Samples: 1,
+                              Reject: true }
+    if deals := never.Determinizations(seen, 20); len(deals) != 20 {
+        t.Errorf("Gave %d of 20 deals when none could be kept.\n", len(deals))
+    }
+    if deals := rare.Determinizations(seen, 0); len(deals) != 1 {
+        t.Errorf("Gave %d deals when none were asked for.\n", len(deals))
+    }
+}
+
+
+/*
+ * A player that plays as another player only once every so many times it is
+ * asked to play, and otherwise plays no card. If every is 0 it never plays as
+ * the other player.
+ */
+type rarePlayer struct {
+    Player
+    every int
+    calls int
+}
+
+
+func (p *rarePlayer) Play(player int, setup euchre.Setup, hand,
+                          played []deck.Card,
+                          prior []euchre.Trick) ([]deck.Card, deck.Card) {
+    p.calls++
+    if p.every == 0 || p.calls % p.every != 0 {
+        return hand, deck.Card{ }
+    }
+
+    return p.Player.Play(player, setup, hand, played, prior)
+}
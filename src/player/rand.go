@@ -3,12 +3,9 @@ package player
 import (
     "deck"
     "euchre"
-    "math/rand"
-    "time"
+    "rng"
 )
 
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
-
 
 /*
  * A RandPlayer. This player is non deterministic, so for the same input, you
@@ -49,7 +46,7 @@ func NewRand(pickupProb float64, callProb float64, aloneProb float64) (*RandPlay
  * Player decides to pickup with probability pickupProb.
  */
 func (p *RandPlayer) Pickup(hand []deck.Card, top deck.Card, who int) bool {
-    return r.Float64() < p.pickupProb
+    return rng.Float64() < p.pickupProb
 }
 
 
@@ -60,7 +57,7 @@ func (p *RandPlayer) Discard(hand []deck.Card, top deck.Card) ([]deck.Card, deck
     hand = append(hand, top)
 
     // Delete a random card not preserving order.
-    i := r.Intn(len(hand))
+    i := rng.Intn(len(hand))
     chosen := hand[i]
     hand[i] = hand[len(hand) - 1]
     hand = hand[:len(hand) - 1]
@@ -75,12 +72,12 @@ func (p *RandPlayer) Discard(hand []deck.Card, top deck.Card) ([]deck.Card, deck
  */
 func (p *RandPlayer) Call(hand []deck.Card, top deck.Card,
                           who int) (deck.Suit, bool) {
-    s := deck.SUITS[r.Intn(len(deck.SUITS))]
+    s := deck.SUITS[rng.Intn(len(deck.SUITS))]
     for s == top.Suit {
-        s = deck.SUITS[r.Intn(len(deck.SUITS))]
+        s = deck.SUITS[rng.Intn(len(deck.SUITS))]
     }
 
-    return s, r.Float64() < p.callProb
+    return s, rng.Float64() < p.callProb
 }
 
 
@@ -89,7 +86,7 @@ func (p *RandPlayer) Call(hand []deck.Card, top deck.Card,
  * probability aloneProb.
  */
 func (p *RandPlayer) Alone(hand []deck.Card, top deck.Card, who int) bool {
-    return r.Float64() < p.aloneProb
+    return rng.Float64() < p.aloneProb
 }
 
 
@@ -98,7 +95,7 @@ func (p *RandPlayer) Play(player int, setup euchre.Setup, hand,
                           prior []euchre.Trick) ([]deck.Card, deck.Card) {
     playable := euchre.Possible(hand, played, setup.Trump)
 
-    chosen := playable[r.Intn(len(playable))]
+    chosen := playable[rng.Intn(len(playable))]
     final := hand[chosen]
     hand[chosen] = hand[len(hand) - 1]
     hand = hand[:len(hand) - 1]
@@ -0,0 +1,77 @@
+package player
+
+import (
+    "ai"
+    "deck"
+    "euchre"
+)
+
+
+/*
+ * An MCTS rollout policy that plays every card the way the RulePlayer would.
+ * This makes playouts much less noisy than uniformly random play. It can be
+ * mixed with random play through ai.EpsilonRollout.
+ */
+type RuleRollout struct {
+    rule RulePlayer
+}
+
+
+/*
+ * Create a rollout policy that follows the RulePlayer's play heuristics.
+ *
+ * Returns:
+ *  A RuleRollout ready to be used in ai.MCTSOptions.
+ */
+func NewRuleRollout() RuleRollout {
+    return RuleRollout{ }
+}
+
+
+func (p RuleRollout) Choose(state ai.TSState, moves []ai.Move) int {
+    cState := state.(euchre.State)
+
+    // The RulePlayer removes the card it plays from the hand, so give it a
+    // copy.
+    hand := make([]deck.Card, len(cState.Hands[cState.Player]))
+    copy(hand, cState.Hands[cState.Player])
+
+    _, card := p.rule.Play(cState.Player, cState.Setup, hand, cState.Played,
+                           cState.Prior)
+    if i := moveIndex(moves, card); i >= 0 {
+        return i
+    }
+
+    // If the engine merges equivalent cards, the card may be given as the card
+    // it is merged into.
+    cur := cState.Hands[cState.Player]
+    possible := euchre.Possible(cur, cState.Played, cState.Setup.Trump)
+    card = euchre.EquivalentTo(card, cur, possible, cState.Prior,
+                               cState.Setup.Trump)
+    if i := moveIndex(moves, card); i >= 0 {
+        return i
+    }
+
+    return 0
+}
+
+
+/*
+ * Find the move that plays a card.
+ *
+ * Args:
+ *  moves: The moves to look through.
+ *  card: The card to find.
+ *
+ * Returns:
+ *  The index of the move whose action is card, or -1 if there is none.
+ */
+func moveIndex(moves []ai.Move, card deck.Card) int {
+    for i, move := range moves {
+        if move.Action == card {
+            return i
+        }
+    }
+
+    return -1
+}
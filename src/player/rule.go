@@ -6,9 +6,8 @@ import (
     "deck"
     "euchre"
     "fmt"
-    "math/rand"
     "os"
-    "time"
+    "rng"
 )
 
 /*
@@ -322,8 +321,7 @@ func (p *RulePlayer) Call(hand []deck.Card, top deck.Card,
  */
 func (p *RulePlayer) Alone(hand []deck.Card, top deck.Card, who int) bool {
     // TODO: For now just use the random approach.
-    r := rand.New(rand.NewSource(time.Now().UnixNano()))
-    return r.Float64() < 0.5
+    return rng.Float64() < 0.5
 }
 
 
@@ -320,3 +320,43 @@ func TestPlay(t *testing.T) {
         }
     }
 }
+
+
+/*
+ * Test that the rule rollout policy picks the same card as the RulePlayer for
+ * every play fixture.
+ */
+func TestRuleRollout(t *testing.T) {
+    rule := NewRule("")
+    rollout := NewRuleRollout()
+
+    for _, merge := range []bool{ false, true } {
+        e := euchre.Engine{ MergeEquivalent: merge }
+        for i, fixture := range playTests {
+            hands := make([][]deck.Card, 4)
+            for j := range hands {
+                hands[j] = make([]deck.Card, 0)
+            }
+            hands[fixture.player] = make([]deck.Card, len(fixture.hand))
+            copy(hands[fixture.player], fixture.hand)
+
+            state := euchre.NewDeterminizedState(fixture.setup, fixture.player,
+                                                 hands, fixture.played,
+                                                 fixture.prior)
+            moves := e.Successors(state)
+            chosen := moves[rollout.Choose(state, moves)].Action
+
+            // With merging, the card the rule player picks may be given as the
+            // card it is merged into.
+            hand := make([]deck.Card, len(fixture.hand))
+            copy(hand, fixture.hand)
+            _, card := rule.Play(fixture.player, fixture.setup, hand,
+                                 fixture.played, fixture.prior)
+            expected := e.Representative(state, card)
+            if chosen != expected {
+                t.Logf("Fixture %d failed with merging %t.\n", i + 1, merge)
+                t.Errorf("Gave %v instead of %s", chosen, expected)
+            }
+        }
+    }
+}
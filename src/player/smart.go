@@ -8,7 +8,31 @@ import (
 )
 
 
+/*
+ * The tree search a SmartPlayer uses to make its decisions.
+ *
+ * DeterminizedSearch builds a separate MCTS tree for each determinization and
+ * combines the results at the root. InformationSetSearch uses a single ISMCTS
+ * tree that is redeterminized on every iteration, and is given the same total
+ * budget of runs times determinizations.
+ */
+type Search int
+const (
+    DeterminizedSearch Search = iota
+    InformationSetSearch
+)
+
+
 type SmartPlayer struct {
+    search Search
+    options ai.MCTSOptions
+    engine euchre.Engine
+    reports []ai.Report
+    reuse bool
+    kept map[int]*keptSearch
+    inference *euchre.Inference
+    weighting *PolicyWeighting
+
     pickupConfidence float64
     callConfidence float64
     aloneConfidence float64
@@ -31,7 +55,8 @@ type SmartPlayer struct {
  * Creates a new SmartPlayer with the given attributes. These attributes are the
  * inputs to the models behind the SmartPlayer's logic. Confidence is on a scale
  * from the maximum possible evaluation to the minimum possible evaluation
- * according to the MCTS evaluation function. Runs is how many times to run a
+ * according to the MCTS evaluation function, and is compared with the mean
+ * reward of the move the search picks. Runs is how many times to run a
  * simulation on a given determinization. Determinizations says how many
  * different determinizations to go through.
  *
@@ -58,6 +83,14 @@ func NewSmart(pickupConfidence float64, callConfidence float64,
               aloneRuns int, aloneDeterminizations int) (*SmartPlayer) {
 
     return &SmartPlayer{
+        DeterminizedSearch,
+        ai.DefaultMCTSOptions(),
+        euchre.Engine{ },
+        nil,
+        false,
+        nil,
+        nil,
+        nil,
         pickupConfidence,
         callConfidence,
         aloneConfidence,
@@ -73,6 +106,99 @@ func NewSmart(pickupConfidence float64, callConfidence float64,
 }
 
 
+/*
+ * Choose the tree search used for all of this player's decisions. The default
+ * is DeterminizedSearch.
+ *
+ * Args:
+ *  search: The tree search to use.
+ */
+func (p *SmartPlayer) SetSearch(search Search) {
+    p.search = search
+    p.kept = nil
+}
+
+
+/*
+ * Set the options, such as the tree policy, for every search this player runs.
+ * The default is ai.DefaultMCTSOptions.
+ *
+ * Args:
+ *  options: The options for the searches.
+ */
+func (p *SmartPlayer) SetOptions(options ai.MCTSOptions) {
+    p.options = options
+}
+
+
+/*
+ * Set the engine for the game logic of every search this player runs, such as
+ * one that merges equivalent cards. The default is an engine with no options
+ * set.
+ *
+ * Args:
+ *  engine: The engine for the searches.
+ */
+func (p *SmartPlayer) SetEngine(engine euchre.Engine) {
+    p.engine = engine
+}
+
+
+/*
+ * Choose whether this player keeps its search between the plays of a hand. If
+ * it does, the tree of each play is moved along the cards that were played
+ * since, and the next play only runs the playouts needed to top it up to the
+ * play runs and determinizations. Determinizations that gave a card to a player
+ * who could not have had it are thrown away. A search is kept for each seat the
+ * player is asked to play for, and is started over when a new hand is seen.
+ * The default is to not keep searches.
+ *
+ * Args:
+ *  reuse: Whether to keep searches between plays.
+ */
+func (p *SmartPlayer) SetReuse(reuse bool) {
+    p.reuse = reuse
+    p.kept = nil
+}
+
+
+/*
+ * Choose whether this player infers where the hidden cards are from the bidding
+ * and play when it plays a card. If it does, its determinizations are drawn
+ * from those beliefs rather than treating every consistent deal alike. The
+ * default is to not infer anything.
+ *
+ * Args:
+ *  inference: How strongly each kind of evidence moves the beliefs, or nil to
+ *             not infer anything.
+ */
+func (p *SmartPlayer) SetInference(inference *euchre.Inference) {
+    p.inference = inference
+}
+
+
+/*
+ * Choose whether this player weights the determinizations of its play searches
+ * by how likely the cards the other players played are in each of them. This
+ * only applies to DeterminizedSearch. A search that is kept between plays is
+ * given new determinizations on every play, since the weights change with each
+ * card that is played, so keeping searches does not save any playouts then.
+ * The default is to not weight determinizations.
+ *
+ * Args:
+ *  weighting: How to weight determinizations, or nil to not weight them.
+ */
+func (p *SmartPlayer) SetWeighting(weighting *PolicyWeighting) {
+    p.weighting = weighting
+    p.kept = nil
+}
+
+
+func (p *SmartPlayer) LastReports() []ai.Report {
+    return p.reports
+}
+
+
 func (p *SmartPlayer) Pickup(hand []deck.Card, top deck.Card, who int) bool {
     var setup euchre.Setup
     var discard deck.Card
@@ -111,8 +237,9 @@ func (p *SmartPlayer) Pickup(hand []deck.Card, top deck.Card, who int) bool {
     nPlayer := (who + 1) % 4
     s := euchre.NewUndeterminizedState(setup, nPlayer, actualHand, played,
                                        prior)
-    e := euchre.Engine{ }
-    _, expected := ai.MCTS(s, e, p.pickupRuns, p.pickupDeterminizations)
+    p.reports = nil
+    e := p.engine
+    _, expected := p.runSearch(s, e, p.pickupRuns, p.pickupDeterminizations)
 
     return (nPlayer % 2 == 0 && expected > p.pickupConfidence) ||
            (nPlayer % 2 == 1 && expected < -1 * p.pickupConfidence)
@@ -200,6 +327,7 @@ func (p *SmartPlayer) Call(hand []deck.Card, top deck.Card,
     prior := make([]euchre.Trick, 0)
     max := math.Inf(-1)
     var maxSuit deck.Suit
+    p.reports = nil
 
     for i := 0; i < len(deck.SUITS); i++ {
         suit := deck.SUITS[i]
@@ -216,8 +344,9 @@ func (p *SmartPlayer) Call(hand []deck.Card, top deck.Card,
             }
 
             s := euchre.NewUndeterminizedState(setup, 0, hand, played, prior)
-            e := euchre.Engine{ }
-            _, expected := ai.MCTS(s, e, p.callRuns, p.callDeterminizations)
+            e := p.engine
+            _, expected := p.runSearch(s, e, p.callRuns,
+                                       p.callDeterminizations)
 
             if expected > max {
                 max = expected
@@ -250,8 +379,9 @@ func (p *SmartPlayer) Alone(hand []deck.Card, top deck.Card, who int) bool {
     }
 
     s := euchre.NewUndeterminizedState(setup, nPlayer, hand, played, prior)
-    e := euchre.Engine{}
-    _, expected := ai.MCTS(s, e, p.aloneRuns, p.aloneDeterminizations)
+    e := p.engine
+    p.reports = nil
+    _, expected := p.runSearch(s, e, p.aloneRuns, p.aloneDeterminizations)
 
     return (nPlayer % 2 == 0 && expected > p.aloneConfidence) ||
            (nPlayer % 2 == 1 && expected < -1 * p.aloneConfidence)
@@ -261,9 +391,24 @@ func (p *SmartPlayer) Alone(hand []deck.Card, top deck.Card, who int) bool {
 func (p *SmartPlayer) Play(player int, setup euchre.Setup, hand,
                            played []deck.Card,
                            prior []euchre.Trick) ([]deck.Card, deck.Card) {
-    s := euchre.NewUndeterminizedState(setup, player, hand, played, prior)
-    e := euchre.Engine{ }
-    chosenMove, _ := ai.MCTS(s, e, p.playRuns, p.playDeterminizations)
+    s := euchre.NewObservedState(setup, player, player, hand, played, prior,
+                                 nil)
+    if p.inference != nil {
+        s.Beliefs = euchre.Infer(s, *p.inference)
+    }
+    e := p.engine
+    p.reports = nil
+
+    var chosenMove ai.Move
+    if p.reuse {
+        chosenMove = p.runKeptSearch(player, s, e)
+    } else if p.weighting != nil && p.search == DeterminizedSearch {
+        report := p.forestReport(ai.NewMCTSForest(), s, e)
+        p.reports = append(p.reports, report)
+        chosenMove, _ = report.Best()
+    } else {
+        chosenMove, _ = p.runSearch(s, e, p.playRuns, p.playDeterminizations)
+    }
 
     card := chosenMove.Action.(deck.Card)
 
@@ -276,3 +421,239 @@ func (p *SmartPlayer) Play(player int, setup euchre.Setup, hand,
 
     return nHand, card
 }
+
+
+/*
+ * Run the tree search this player is configured with. The report of the search
+ * is kept for LastReports.
+ *
+ * Args:
+ *  s: The undeterminized state to search from.
+ *  e: The engine for the game logic.
+ *  runs: The amount of times to run each determinization.
+ *  deters: The amount of determinizations.
+ *
+ * Returns:
+ *  The chosen move and its mean reward from the point of view of the player to
+ *  move.
+ */
+func (p *SmartPlayer) runSearch(s euchre.State, e euchre.Engine, runs,
+                                deters int) (ai.Move, float64) {
+    var report ai.Report
+    if p.search == InformationSetSearch {
+        report = ai.ISMCTSReport(s, e, runs * deters, p.options)
+    } else {
+        report = ai.MCTSReport(s, e, runs, deters, p.options)
+    }
+
+    p.reports = append(p.reports, report)
+    return report.Best()
+}
+
+
+/*
+ * The search that a SmartPlayer keeps for one seat between the plays of a
+ * hand. The setup, the hand that was left, the cards played before the search
+ * and the card that was chosen are what the next play must continue from for
+ * the search to be used again.
+ */
+type keptSearch struct {
+    setup euchre.Setup
+    hand []deck.Card
+    history []deck.Card
+    card deck.Card
+
+    forest *ai.MCTSForest
+    tree *ai.ISMCTSTree
+}
+
+
+/*
+ * Whether a play continues the hand that a search was kept from. This is the
+ * case if the setup is the same, the hand is what was left after the last
+ * play, and the cards played so far start with the cards that were played
+ * before the last play and then the card that was chosen.
+ *
+ * Args:
+ *  setup: The setup of the play.
+ *  hand: The hand of the play.
+ *  history: Every card played in the hand so far, in order.
+ *
+ * Returns:
+ *  True if the search can be used for the play.
+ */
+func (k *keptSearch) follows(setup euchre.Setup, hand,
+                             history []deck.Card) bool {
+    if setup != k.setup || len(hand) != len(k.hand) ||
+       len(history) <= len(k.history) || history[len(k.history)] != k.card {
+        return false
+    }
+
+    // The cards in a hand are all different, so the hands are the same if
+    // every card of one is in the other.
+    for _, card := range hand {
+        found := false
+        for _, keptCard := range k.hand {
+            found = found || card == keptCard
+        }
+
+        if !found {
+            return false
+        }
+    }
+
+    for i, card := range k.history {
+        if history[i] != card {
+            return false
+        }
+    }
+
+    return true
+}
+
+
+/*
+ * Run the play search for a seat with the search that was kept from its last
+ * play, if this play continues the same hand, and keep the search for the
+ * next play. The report of the search is kept for LastReports.
+ *
+ * Args:
+ *  player: The seat that is playing.
+ *  s: The undeterminized state to search from.
+ *  e: The engine for the game logic.
+ *
+ * Returns:
+ *  The chosen move.
+ */
+func (p *SmartPlayer) runKeptSearch(player int, s euchre.State,
+                                    e euchre.Engine) ai.Move {
+    history := make([]deck.Card, 0)
+    for _, trick := range s.Prior {
+        history = append(history, trick.Cards...)
+    }
+    history = append(history, s.Played...)
+
+    if p.kept == nil {
+        p.kept = make(map[int]*keptSearch)
+    }
+
+    kept, ok := p.kept[player]
+    if ok && kept.follows(s.Setup, s.Hands[s.Observer], history) {
+        actions := make([]interface{}, 0)
+        for _, card := range history[len(kept.history):] {
+            actions = append(actions, card)
+        }
+
+        // Weighted searches draw new trees on every play, so only the tree
+        // that is searched is moved along.
+        if p.search == InformationSetSearch {
+            kept.tree.Advance(e, playedFrom(s, len(actions)), actions)
+        } else if p.weighting == nil {
+            kept.forest.Advance(e, actions)
+        }
+    } else {
+        kept = &keptSearch {
+            setup: s.Setup,
+            forest: ai.NewMCTSForest(),
+            tree: ai.NewISMCTSTree(),
+        }
+        p.kept[player] = kept
+    }
+
+    var report ai.Report
+    if p.search == InformationSetSearch {
+        report = kept.tree.Report(s, e, p.playRuns * p.playDeterminizations,
+                                  p.options)
+    } else {
+        report = p.forestReport(kept.forest, s, e)
+    }
+    p.reports = append(p.reports, report)
+
+    move, _ := report.Best()
+    card := move.Action.(deck.Card)
+
+    kept.history = history
+    kept.card = card
+    kept.hand = make([]deck.Card, 0, len(s.Hands[s.Observer]))
+    for _, c := range s.Hands[s.Observer] {
+        if c != card {
+            kept.hand = append(kept.hand, c)
+        }
+    }
+
+    return move
+}
+
+
+/*
+ * Find states that the last cards of a hand could have been played from. The
+ * hands the other players have now are drawn once, and every card is given
+ * back to the player who played it.
+ *
+ * Args:
+ *  s: The undeterminized state after the cards were played.
+ *  n: The number of cards, counted back from the last one played.
+ *
+ * Returns:
+ *  For each of the last n cards in the order they were played, a determinized
+ *  state in which it is about to be played.
+ */
+func playedFrom(s euchre.State, n int) []ai.TSState {
+    d := s.Copy().(euchre.State)
+    d.Determinize()
+
+    alone := s.Setup.AlonePlayer
+    tricks := make([]euchre.Trick, len(s.Prior), len(s.Prior) + 1)
+    copy(tricks, s.Prior)
+    tricks = append(tricks, euchre.Trick {
+        s.Played,
+        euchre.Leader(s.Played, s.Player, alone),
+        s.Setup.Trump,
+        alone,
+    })
+
+    states := make([]ai.TSState, n)
+    hands := d.Hands
+    for t := len(tricks) - 1; t >= 0 && n > 0; t-- {
+        cards := tricks[t].Cards
+        seats := euchre.PlayOrder(tricks[t].Led, len(cards), alone)
+        for i := len(cards) - 1; i >= 0 && n > 0; i-- {
+            given := make([][]deck.Card, len(hands))
+            for j := range hands {
+                given[j] = append([]deck.Card{ }, hands[j]...)
+            }
+            given[seats[i]] = append(given[seats[i]], cards[i])
+            hands = given
+
+            n--
+            states[n] = euchre.NewDeterminizedState(s.Setup, seats[i], hands,
+                                                    cards[:i:i], tricks[:t:t])
+        }
+    }
+
+    return states
+}
+
+
+/*
+ * Run the play search of a forest, with weighted determinizations if this
+ * player weights them.
+ *
+ * Args:
+ *  forest: The forest to search with.
+ *  s: The undeterminized state to search from.
+ *  e: The engine for the game logic.
+ *
+ * Returns:
+ *  The report of the search.
+ */
+func (p *SmartPlayer) forestReport(forest *ai.MCTSForest, s euchre.State,
+                                   e euchre.Engine) ai.Report {
+    if p.weighting == nil {
+        return forest.Report(s, e, p.playRuns, p.playDeterminizations,
+                             p.options)
+    }
+
+    deals := p.weighting.Determinizations(s, p.playDeterminizations)
+    return forest.WeightedReport(deals, e, p.playRuns, p.options)
+}
@@ -0,0 +1,291 @@
+package player
+
+import (
+    "ai"
+    "deck"
+    "euchre"
+    "math"
+    "rng"
+)
+
+
+/*
+ * The most deals drawn for each determinization that is asked for when deals
+ * are rejected, before giving up on finding more.
+ */
+const rejectTries = 100
+
+
+/*
+ * The number of deals drawn for each determinization that is asked for when
+ * deals are rejected, before any is kept, to find how likely a deal can be.
+ */
+const rejectPool = 4
+
+
+/*
+ * A sampler of determinizations that are consistent with how the other players
+ * are expected to play. Model stands in for every player other than the
+ * observer of the state.
+ * Each candidate deal is replayed from the first trick, and at each card that
+ * another player played, Model is asked what it would have played with the
+ * hand that player had in that deal. The likelihood of the deal is the product
+ * over those cards of the chance Model plays the same card.
+ *
+ * Model is asked Samples times at each card, so that a random player has a
+ * chance in proportion to how often it agrees. The order of a hidden hand is
+ * not known, so the hand is shuffled each time, and a model that breaks ties
+ * by the order of the hand splits its chance between the tied cards. Epsilon is
+ * the chance a player ignores the model and plays any legal card, so that a
+ * deal the model can not explain is unlikely rather than impossible. If Reject
+ * is set, each deal is kept with a chance of its likelihood over the highest
+ * likelihood drawn so far, and every kept deal has the same weight. Otherwise
+ * every drawn deal is kept and weighted by its likelihood.
+ */
+type PolicyWeighting struct {
+    Model Player
+    Samples int
+    Epsilon float64
+    Reject bool
+}
+
+
+/*
+ * The likelihood of the plays of the other players in a deal.
+ *
+ * Args:
+ *  s: A determinized state.
+ *
+ * Returns:
+ *  The chance that the other players, playing as the model, would have played
+ *  every card they did with the hands they have in s.
+ */
+func (w PolicyWeighting) Likelihood(s euchre.State) float64 {
+    alone := s.Setup.AlonePlayer
+    tricks := make([]euchre.Trick, len(s.Prior), len(s.Prior) + 1)
+    copy(tricks, s.Prior)
+    tricks = append(tricks, euchre.Trick {
+        s.Played,
+        euchre.Leader(s.Played, s.Player, alone),
+        s.Setup.Trump,
+        alone,
+    })
+
+    // Give back every card each player has played to find the hands they
+    // started the first trick with.
+    hands := make([][]deck.Card, 4)
+    for player := range hands {
+        if player != s.Observer {
+            hands[player] = append([]deck.Card{ }, s.Hands[player]...)
+        }
+    }
+    for _, trick := range tricks {
+        seats := euchre.PlayOrder(trick.Led, len(trick.Cards), trick.Alone)
+        for i, card := range trick.Cards {
+            if seats[i] != s.Observer {
+                hands[seats[i]] = append(hands[seats[i]], card)
+            }
+        }
+    }
+
+    likelihood := 1.0
+    for t, trick := range tricks {
+        seats := euchre.PlayOrder(trick.Led, len(trick.Cards), trick.Alone)
+        for i, card := range trick.Cards {
+            player := seats[i]
+            if player == s.Observer {
+                continue
+            }
+
+            played := trick.Cards[:i:i]
+            likelihood *= w.playLikelihood(player, s.Setup, hands[player],
+                                           played, tricks[:t], card)
+            if likelihood == 0 {
+                return 0
+            }
+
+            hands[player] = removeCard(hands[player], card)
+        }
+    }
+
+    return likelihood
+}
+
+
+/*
+ * Draw determinizations of a state weighted by the likelihood of the plays of
+ * the other players. If the state has no more deals than asked for, every deal
+ * is given instead, with its weight times its likelihood.
+ *
+ * Args:
+ *  s: The undeterminized state.
+ *  n: The number of determinizations to draw, which is at least 1.
+ *
+ * Returns:
+ *  The determinizations with weights that sum to 1. When deals are rejected
+ *  and fewer than n are kept within the tries allowed, only those that were
+ *  kept are given. If none are kept, n deals are given with the same weight,
+ *  as if they were not weighted.
+ */
+func (w PolicyWeighting) Determinizations(s euchre.State,
+                                          n int) []ai.Determinization {
+    if n < 1 {
+        n = 1
+    }
+
+    if deals := s.Determinizations(n); deals != nil {
+        for i := range deals {
+            deals[i].Weight *= w.Likelihood(deals[i].State.(euchre.State))
+        }
+
+        return normalizeWeights(deals)
+    }
+
+    draw := func() ai.Determinization {
+        d := s.Copy().(euchre.State)
+        d.Determinize()
+        return ai.Determinization{ d, w.Likelihood(d) }
+    }
+
+    if !w.Reject {
+        deals := make([]ai.Determinization, n)
+        for i := range deals {
+            deals[i] = draw()
+        }
+
+        return normalizeWeights(deals)
+    }
+
+    // Likelihoods are products over every card played, so they are far below
+    // 1. Scaling them by the highest one seen keeps the most likely deals.
+    pool := make([]ai.Determinization, rejectPool * n)
+    bound := 0.0
+    for i := range pool {
+        pool[i] = draw()
+        bound = math.Max(bound, pool[i].Weight)
+    }
+
+    // A deal with a likelihood of 0 is never kept, so there is no use in
+    // drawing more if every deal so far has one.
+    tries := rejectTries * n
+    if bound == 0 {
+        tries = 0
+    }
+
+    kept := make([]ai.Determinization, 0, n)
+    for i := 0; len(kept) < n && i < tries; i++ {
+        var deal ai.Determinization
+        if i < len(pool) {
+            deal = pool[i]
+        } else {
+            deal = draw()
+            bound = math.Max(bound, deal.Weight)
+        }
+
+        if rng.Float64() * bound < deal.Weight {
+            kept = append(kept, ai.Determinization{ deal.State, 1 })
+        }
+    }
+
+    if len(kept) == 0 {
+        for _, deal := range pool[:n] {
+            kept = append(kept, ai.Determinization{ deal.State, 1 })
+        }
+    }
+
+    return normalizeWeights(kept)
+}
+
+
+/*
+ * The chance that the model plays a card.
+ *
+ * Args:
+ *  player: The player who played the card.
+ *  setup: The setup of the hand.
+ *  hand: The hand of the player before the card was played.
+ *  played: The cards played to the trick before it.
+ *  prior: The tricks before it.
+ *  card: The card that was played.
+ *
+ * Returns:
+ *  The share of the samples in which the model played card, mixed with a
+ *  chance of Epsilon of a legal card at random.
+ */
+func (w PolicyWeighting) playLikelihood(player int, setup euchre.Setup, hand,
+                                        played []deck.Card,
+                                        prior []euchre.Trick,
+                                        card deck.Card) float64 {
+    samples := w.Samples
+    if samples < 1 {
+        samples = 1
+    }
+
+    matches := 0
+    for i := 0; i < samples; i++ {
+        // Players remove the card they play from the hand, so give them a
+        // shuffled copy.
+        copyHand := make([]deck.Card, len(hand))
+        for j, k := range rng.Perm(len(hand)) {
+            copyHand[j] = hand[k]
+        }
+
+        if _, chosen := w.Model.Play(player, setup, copyHand, played,
+                                     prior); chosen == card {
+            matches++
+        }
+    }
+
+    legal := euchre.Possible(hand, played, setup.Trump)
+    return (1 - w.Epsilon) * float64(matches) / float64(samples) +
+           w.Epsilon / float64(len(legal))
+}
+
+
+/*
+ * Scale the weights of determinizations to sum to 1, or make them all the same
+ * if they sum to 0.
+ *
+ * Args:
+ *  deals: The determinizations, which are changed in place.
+ *
+ * Returns:
+ *  The same determinizations.
+ */
+func normalizeWeights(deals []ai.Determinization) []ai.Determinization {
+    total := 0.0
+    for _, deal := range deals {
+        total += deal.Weight
+    }
+
+    for i := range deals {
+        if total > 0 {
+            deals[i].Weight /= total
+        } else {
+            deals[i].Weight = 1 / float64(len(deals))
+        }
+    }
+
+    return deals
+}
+
+
+/*
+ * Remove the first copy of a card from a hand.
+ *
+ * Args:
+ *  hand: The hand, which is changed.
+ *  card: The card to remove.
+ *
+ * Returns:
+ *  The hand without the card.
+ */
+func removeCard(hand []deck.Card, card deck.Card) []deck.Card {
+    for i, c := range hand {
+        if c == card {
+            return append(hand[:i], hand[i + 1:]...)
+        }
+    }
+
+    return hand
+}
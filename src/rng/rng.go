@@ -0,0 +1,108 @@
+package rng
+
+import (
+    "math/rand"
+    "time"
+)
+
+/*
+ * All of the randomness in the bot goes through this package. Previously each
+ * package created its own generator seeded from the clock, which made any
+ * result impossible to reproduce. Now there is a single source that defaults
+ * to a clock based seed, but can be reseeded, or replaced entirely, before a
+ * run starts. Note that, like the generators it replaces, the source is not
+ * safe for concurrent use.
+ */
+
+
+var seed = time.Now().UnixNano()
+var r = rand.New(rand.NewSource(seed))
+
+
+/*
+ * Reseed the shared source. Every random decision made after this call is
+ * reproducible from the given seed.
+ *
+ * Args:
+ *  s: The seed to use for the shared source.
+ */
+func Seed(s int64) {
+    seed = s
+    r = rand.New(rand.NewSource(s))
+}
+
+
+/*
+ * Replace the shared source with the provided one. This is useful when a caller
+ * wants full control over the random stream, such as in tests. The seed that
+ * is reported by GetSeed is left untouched, since it is not known.
+ *
+ * Args:
+ *  src: The source that all randomness should now come from.
+ */
+func SetSource(src rand.Source) {
+    r = rand.New(src)
+}
+
+
+/*
+ * The seed the shared source was last seeded with. This is the value that
+ * should be recorded to replay a run.
+ *
+ * Returns:
+ *  The seed of the shared source.
+ */
+func GetSeed() int64 {
+    return seed
+}
+
+
+/*
+ * Provides the shared generator itself for callers that need the full
+ * rand.Rand API.
+ *
+ * Returns:
+ *  The shared random generator.
+ */
+func Rand() *rand.Rand {
+    return r
+}
+
+
+/*
+ * Returns a random int in [0, n) from the shared source.
+ *
+ * Args:
+ *  n: The exclusive upper bound. Must be positive.
+ *
+ * Returns:
+ *  A pseudo-random number in [0, n).
+ */
+func Intn(n int) int {
+    return r.Intn(n)
+}
+
+
+/*
+ * Returns a random float in [0.0, 1.0) from the shared source.
+ *
+ * Returns:
+ *  A pseudo-random number in [0.0, 1.0).
+ */
+func Float64() float64 {
+    return r.Float64()
+}
+
+
+/*
+ * Returns a random permutation of the integers [0, n) from the shared source.
+ *
+ * Args:
+ *  n: The number of integers to permute.
+ *
+ * Returns:
+ *  A slice of n ints that is a permutation of [0, n).
+ */
+func Perm(n int) []int {
+    return r.Perm(n)
+}